@@ -0,0 +1,119 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package check
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	cmv1 "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	cmmeta "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"github.com/cilium/cilium/cilium-cli/utils/features"
+)
+
+// certManagerSecretTimeout bounds how long WithCertManagerCertificate waits
+// for cert-manager to issue the certificate and populate its Secret.
+const certManagerSecretTimeout = 2 * time.Minute
+
+// WithCertManagerCertificateParams configures a certificate requested from a
+// cert-manager Issuer/ClusterIssuer already present in the cluster, for use
+// with WithCertManagerCertificate.
+type WithCertManagerCertificateParams struct {
+	// Name is the name of both the generated cert-manager Certificate
+	// resource and the Secret it's issued into; it's also the key under
+	// which the certificate is registered in CertificateCAs/CertificateKeys,
+	// the same way WithCertificate's name parameter is.
+	Name string
+
+	// IssuerName is the name of the Issuer or ClusterIssuer to request the
+	// certificate from.
+	IssuerName string
+
+	// IssuerKind is the kind of the issuer: "Issuer" or "ClusterIssuer".
+	// Defaults to "ClusterIssuer" when empty.
+	IssuerKind string
+
+	// DNSNames are the DNS SANs requested for the certificate.
+	DNSNames []string
+}
+
+// WithCertManagerCertificate requests a certificate from a cert-manager
+// Issuer/ClusterIssuer already installed in the cluster, rather than minting
+// a self-signed one in-process the way WithCertificate does. It creates a
+// cert-manager Certificate resource, waits for the resulting Secret to
+// appear, and registers the Secret's ca.crt in CertificateCAs and the issued
+// certificate's own private key (tls.key) in CertificateKeys; the issuer's CA
+// private key is not available and is not registered. A finalizer deletes
+// the Certificate resource (and, with it, its Secret) on test teardown.
+func (t *Test) WithCertManagerCertificate(params WithCertManagerCertificateParams) *Test {
+	if params.IssuerKind == "" {
+		params.IssuerKind = "ClusterIssuer"
+	}
+
+	t.WithFeatureRequirements(features.RequireEnabled(features.CertManager))
+
+	t.before = append(t.before, func(ctx context.Context, t *Test, testCtx *ConnectivityTest) error {
+		namespace := t.ctx.params.TestNamespace
+
+		cert := &cmv1.Certificate{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      params.Name,
+				Namespace: namespace,
+			},
+			Spec: cmv1.CertificateSpec{
+				SecretName: params.Name,
+				DNSNames:   params.DNSNames,
+				IssuerRef: cmmeta.ObjectReference{
+					Name: params.IssuerName,
+					Kind: params.IssuerKind,
+				},
+			},
+		}
+
+		for _, client := range testCtx.Clients() {
+			if err := client.CreateCertManagerCertificate(ctx, namespace, cert, metav1.CreateOptions{}); err != nil {
+				return fmt.Errorf("creating cert-manager Certificate %q: %w", params.Name, err)
+			}
+
+			t.finalizers = append(t.finalizers, func(ctx context.Context) error {
+				return client.DeleteCertManagerCertificate(ctx, namespace, params.Name, metav1.DeleteOptions{})
+			})
+
+			var secret *corev1.Secret
+			err := wait.PollUntilContextTimeout(ctx, time.Second, certManagerSecretTimeout, true, func(ctx context.Context) (bool, error) {
+				s, err := client.GetSecret(ctx, namespace, params.Name, metav1.GetOptions{})
+				if err != nil {
+					return false, nil //nolint:nilerr // keep polling until the timeout; cert-manager hasn't issued the Secret yet
+				}
+				if len(s.Data[corev1.TLSCertKey]) == 0 {
+					return false, nil
+				}
+				secret = s
+				return true, nil
+			})
+			if err != nil {
+				return fmt.Errorf("waiting for cert-manager Secret %q: %w", params.Name, err)
+			}
+
+			if t.certificateCAs == nil {
+				t.certificateCAs = make(map[string][]byte)
+			}
+			t.certificateCAs[params.Name] = secret.Data["ca.crt"]
+
+			if t.certificateKeys == nil {
+				t.certificateKeys = make(map[string][]byte)
+			}
+			t.certificateKeys[params.Name] = secret.Data[corev1.TLSPrivateKeyKey]
+		}
+
+		return nil
+	})
+
+	return t
+}
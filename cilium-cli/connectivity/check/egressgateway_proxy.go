@@ -0,0 +1,126 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package check
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	ciliumv2 "github.com/cilium/cilium/pkg/k8s/apis/cilium.io/v2"
+)
+
+// HTTPProxyConfigMapRef identifies a ConfigMap carrying HTTP_PROXY,
+// HTTPS_PROXY and NO_PROXY entries to honor when
+// CiliumEgressGatewayPolicyParams.HonorClusterHTTPProxy is set.
+type HTTPProxyConfigMapRef struct {
+	Namespace string
+	Name      string
+}
+
+// defaultHTTPProxyConfigMapRef is consulted when HonorClusterHTTPProxy is set
+// without a HTTPProxyConfigMap override.
+var defaultHTTPProxyConfigMapRef = HTTPProxyConfigMapRef{Namespace: "kube-system", Name: "proxy-config"}
+
+// resolveClusterHTTPProxyCIDRs reads the HTTP_PROXY/HTTPS_PROXY/NO_PROXY
+// entries out of the ConfigMap identified by ref and turns them into
+// destination CIDRs (covering the proxy endpoints) and excluded CIDRs
+// (covering the NO_PROXY list), for use in a CiliumEgressGatewayPolicy.
+func (t *Test) resolveClusterHTTPProxyCIDRs(ctx context.Context, ref HTTPProxyConfigMapRef, ipv6Enabled bool) (destinationCIDRs, excludedCIDRs []ciliumv2.CIDR, err error) {
+	seenDest := map[string]struct{}{}
+	seenExcl := map[string]struct{}{}
+
+	for _, client := range t.Context().Clients() {
+		cm, err := client.GetConfigMap(ctx, ref.Namespace, ref.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, nil, fmt.Errorf("fetching ConfigMap %s/%s: %w", ref.Namespace, ref.Name, err)
+		}
+
+		for _, key := range []string{"HTTP_PROXY", "HTTPS_PROXY", "http_proxy", "https_proxy"} {
+			raw, ok := cm.Data[key]
+			if !ok || raw == "" {
+				continue
+			}
+			cidr, err := proxyEndpointCIDR(raw, ipv6Enabled)
+			if err != nil {
+				return nil, nil, fmt.Errorf("parsing %s=%q: %w", key, raw, err)
+			}
+			if cidr == "" {
+				continue
+			}
+			if _, ok := seenDest[cidr]; !ok {
+				seenDest[cidr] = struct{}{}
+				destinationCIDRs = append(destinationCIDRs, ciliumv2.CIDR(cidr))
+			}
+		}
+
+		for _, key := range []string{"NO_PROXY", "no_proxy"} {
+			raw, ok := cm.Data[key]
+			if !ok || raw == "" {
+				continue
+			}
+			for _, entry := range strings.Split(raw, ",") {
+				entry = strings.TrimSpace(entry)
+				if entry == "" {
+					continue
+				}
+				cidr, err := noProxyEntryCIDR(entry, ipv6Enabled)
+				if err != nil || cidr == "" {
+					// Hostnames and wildcard domains (".example.com") can't be
+					// expressed as a CIDR; skip them rather than failing the
+					// whole policy setup.
+					continue
+				}
+				if _, ok := seenExcl[cidr]; !ok {
+					seenExcl[cidr] = struct{}{}
+					excludedCIDRs = append(excludedCIDRs, ciliumv2.CIDR(cidr))
+				}
+			}
+		}
+	}
+
+	return destinationCIDRs, excludedCIDRs, nil
+}
+
+// proxyEndpointCIDR turns a HTTP_PROXY/HTTPS_PROXY URL into a host CIDR
+// (/32 or /128) covering the proxy's address. It returns an empty string,
+// with no error, if the proxy host is not a literal IP address.
+func proxyEndpointCIDR(rawURL string, ipv6Enabled bool) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	host := u.Hostname()
+	return hostCIDR(host, ipv6Enabled)
+}
+
+// noProxyEntryCIDR turns a single NO_PROXY entry into a CIDR. Entries that
+// are already a CIDR are returned as-is; bare IPs become a host CIDR.
+func noProxyEntryCIDR(entry string, ipv6Enabled bool) (string, error) {
+	if strings.Contains(entry, "/") {
+		if _, _, err := net.ParseCIDR(entry); err != nil {
+			return "", err
+		}
+		return entry, nil
+	}
+	return hostCIDR(entry, ipv6Enabled)
+}
+
+func hostCIDR(host string, ipv6Enabled bool) (string, error) {
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return "", nil
+	}
+	if ip.To4() != nil {
+		return fmt.Sprintf("%s/32", ip.String()), nil
+	}
+	if !ipv6Enabled {
+		return "", nil
+	}
+	return fmt.Sprintf("%s/128", ip.String()), nil
+}
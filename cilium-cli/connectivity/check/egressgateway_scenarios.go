@@ -0,0 +1,319 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package check
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/cilium/cilium/cilium-cli/utils/features"
+)
+
+// egressGatewayNodeIP resolves a Kubernetes node name (as returned by
+// EgressGatewayNode()/EgressGatewayNodes()) to the address SNATed traffic
+// from that node is observed to arrive with, by looking up the node's
+// InternalIP (falling back to ExternalIP) in its Status.Addresses. This is
+// necessary because the gateway node's name can never equal the dotted-quad
+// source IP parsed out of a probe response.
+func egressGatewayNodeIP(ctx context.Context, t *Test, nodeName string) (string, error) {
+	node, err := t.Context().Clients()[0].GetNode(ctx, nodeName, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("getting node %s: %w", nodeName, err)
+	}
+
+	var externalIP string
+	for _, addr := range node.Status.Addresses {
+		switch addr.Type {
+		case corev1.NodeInternalIP:
+			return addr.Address, nil
+		case corev1.NodeExternalIP:
+			externalIP = addr.Address
+		}
+	}
+	if externalIP != "" {
+		return externalIP, nil
+	}
+
+	return "", fmt.Errorf("node %s has no InternalIP or ExternalIP in status.addresses", nodeName)
+}
+
+// egressGatewayProbeSourceIP execs a curl against target's /ip endpoint from
+// within client, and returns the source IP address target observed the
+// connection arrive from. It's the common primitive behind the egress
+// gateway scenarios below: each one is ultimately a question of "what source
+// IP did the external target see?".
+func egressGatewayProbeSourceIP(ctx context.Context, t *Test, client *Pod, target string) (string, error) {
+	cmd := []string{"curl", "--silent", "--max-time", "5", fmt.Sprintf("http://%s/ip", target)}
+
+	stdout, err := t.Context().Clients()[0].ExecInPod(ctx, client.Pod.Namespace, client.Pod.Name, client.Pod.Labels["name"], cmd)
+	if err != nil {
+		return "", fmt.Errorf("execing into %s: %w", client.Pod.Name, err)
+	}
+
+	ip := net.ParseIP(strings.TrimSpace(stdout.String()))
+	if ip == nil {
+		return "", fmt.Errorf("target %s did not return a parseable source IP, got %q", target, stdout.String())
+	}
+	return ip.String(), nil
+}
+
+// egressGatewayExcludedCIDRs exercises a CiliumEgressGatewayPolicy whose
+// destinationCIDRs cover a broad range (e.g. 1.1.1.0/24) carved up by
+// excludedCIDRs (e.g. 1.1.1.22/32, 1.1.1.240/30). It asserts that traffic to
+// an excluded destination bypasses the gateway (the target sees a source IP
+// other than the gateway's) while traffic to a non-excluded destination in
+// the same CIDR egresses via the gateway's IP.
+type egressGatewayExcludedCIDRs struct {
+	// ExcludedTarget is a destination within the policy's excludedCIDRs;
+	// traffic to it must not be SNATed.
+	ExcludedTarget string
+
+	// NonExcludedTarget is a destination within destinationCIDRs but
+	// outside excludedCIDRs; traffic to it must be SNATed to the gateway IP.
+	NonExcludedTarget string
+}
+
+func (egressGatewayExcludedCIDRs) Name() string {
+	return "egress-gateway-excluded-cidrs"
+}
+
+func (egressGatewayExcludedCIDRs) Requirements() []features.Requirement {
+	return []features.Requirement{features.RequireEnabled(features.EgressGateway)}
+}
+
+func (s egressGatewayExcludedCIDRs) Run(ctx context.Context, t *Test) {
+	gatewayNode := t.EgressGatewayNode()
+	if gatewayNode == "" {
+		t.Fatalf("Cannot find egress gateway node")
+	}
+	gatewayIP, err := egressGatewayNodeIP(ctx, t, gatewayNode)
+	if err != nil {
+		t.Fatalf("Resolving egress gateway node %s address: %s", gatewayNode, err)
+	}
+
+	for _, client := range t.ctx.clientPods {
+		a := t.NewGenericAction(s, fmt.Sprintf("excluded-cidr-%s", client.Pod.Name))
+		observed, err := egressGatewayProbeSourceIP(ctx, t, &client, s.ExcludedTarget)
+		if err != nil {
+			a.Failf("Probing excluded target %s: %s", s.ExcludedTarget, err)
+			continue
+		}
+		if observed == gatewayIP {
+			a.Failf("Excluded destination %s was SNATed to gateway IP %s, expected client Pod IP", s.ExcludedTarget, gatewayIP)
+		}
+
+		a = t.NewGenericAction(s, fmt.Sprintf("non-excluded-cidr-%s", client.Pod.Name))
+		observed, err = egressGatewayProbeSourceIP(ctx, t, &client, s.NonExcludedTarget)
+		if err != nil {
+			a.Failf("Probing non-excluded target %s: %s", s.NonExcludedTarget, err)
+			continue
+		}
+		if observed != gatewayIP {
+			a.Failf("Non-excluded destination %s was not SNATed to gateway IP %s, observed %s", s.NonExcludedTarget, gatewayIP, observed)
+		}
+	}
+}
+
+// egressGatewayCatchAll exercises a CiliumEgressGatewayPolicy with a
+// catch-all 0.0.0.0/0 destinationCIDRs. It asserts that traffic to
+// node-local/PodCIDR/ClusterCIDR destinations is *not* SNATed (those stay
+// on the in-cluster, Cilium-managed path) while traffic to a destination
+// outside the cluster egresses via the gateway IP.
+type egressGatewayCatchAll struct {
+	// ClusterTarget is a destination within the cluster (node-local Pod,
+	// PodCIDR or ClusterCIDR); traffic to it must not be SNATed.
+	ClusterTarget string
+
+	// ExternalTarget is a destination outside the cluster; traffic to it
+	// must be SNATed to the gateway IP.
+	ExternalTarget string
+}
+
+func (egressGatewayCatchAll) Name() string {
+	return "egress-gateway-catch-all"
+}
+
+func (egressGatewayCatchAll) Requirements() []features.Requirement {
+	return []features.Requirement{features.RequireEnabled(features.EgressGateway)}
+}
+
+func (s egressGatewayCatchAll) Run(ctx context.Context, t *Test) {
+	gatewayNode := t.EgressGatewayNode()
+	if gatewayNode == "" {
+		t.Fatalf("Cannot find egress gateway node")
+	}
+	gatewayIP, err := egressGatewayNodeIP(ctx, t, gatewayNode)
+	if err != nil {
+		t.Fatalf("Resolving egress gateway node %s address: %s", gatewayNode, err)
+	}
+
+	for _, client := range t.ctx.clientPods {
+		a := t.NewGenericAction(s, fmt.Sprintf("cluster-local-%s", client.Pod.Name))
+		observed, err := egressGatewayProbeSourceIP(ctx, t, &client, s.ClusterTarget)
+		if err != nil {
+			a.Failf("Probing cluster-local target %s: %s", s.ClusterTarget, err)
+			continue
+		}
+		if observed == gatewayIP {
+			a.Failf("Cluster-local destination %s was SNATed to gateway IP %s under a catch-all policy", s.ClusterTarget, gatewayIP)
+		}
+
+		a = t.NewGenericAction(s, fmt.Sprintf("external-%s", client.Pod.Name))
+		observed, err = egressGatewayProbeSourceIP(ctx, t, &client, s.ExternalTarget)
+		if err != nil {
+			a.Failf("Probing external target %s: %s", s.ExternalTarget, err)
+			continue
+		}
+		if observed != gatewayIP {
+			a.Failf("External destination %s was not SNATed to gateway IP %s under a catch-all policy, observed %s", s.ExternalTarget, gatewayIP, observed)
+		}
+	}
+}
+
+// egressGatewayHTTPProxy exercises a CiliumEgressGatewayPolicy built with
+// CiliumEgressGatewayPolicyParams.HonorClusterHTTPProxy. It asserts that
+// traffic to the cluster's configured HTTP(S) proxy endpoint is SNATed via
+// the egress gateway (the proxy itself must be reachable through the
+// gateway) while traffic to a NO_PROXY destination bypasses it (the target
+// sees a source IP other than the gateway's, since NO_PROXY destinations are
+// carved out of the policy's destinationCIDRs via excludedCIDRs).
+type egressGatewayHTTPProxy struct {
+	// ProxyTarget is the cluster's configured HTTP(S) proxy endpoint;
+	// traffic to it must be SNATed to the gateway IP.
+	ProxyTarget string
+
+	// NoProxyTarget is a destination covered by the cluster's NO_PROXY
+	// configuration; traffic to it must not be SNATed.
+	NoProxyTarget string
+}
+
+func (egressGatewayHTTPProxy) Name() string {
+	return "egress-gateway-http-proxy"
+}
+
+func (egressGatewayHTTPProxy) Requirements() []features.Requirement {
+	return []features.Requirement{features.RequireEnabled(features.EgressGateway)}
+}
+
+func (s egressGatewayHTTPProxy) Run(ctx context.Context, t *Test) {
+	gatewayNode := t.EgressGatewayNode()
+	if gatewayNode == "" {
+		t.Fatalf("Cannot find egress gateway node")
+	}
+	gatewayIP, err := egressGatewayNodeIP(ctx, t, gatewayNode)
+	if err != nil {
+		t.Fatalf("Resolving egress gateway node %s address: %s", gatewayNode, err)
+	}
+
+	for _, client := range t.ctx.clientPods {
+		a := t.NewGenericAction(s, fmt.Sprintf("proxy-endpoint-%s", client.Pod.Name))
+		observed, err := egressGatewayProbeSourceIP(ctx, t, &client, s.ProxyTarget)
+		if err != nil {
+			a.Failf("Probing proxy target %s: %s", s.ProxyTarget, err)
+			continue
+		}
+		if observed != gatewayIP {
+			a.Failf("Proxy destination %s was not SNATed to gateway IP %s, observed %s", s.ProxyTarget, gatewayIP, observed)
+		}
+
+		a = t.NewGenericAction(s, fmt.Sprintf("no-proxy-%s", client.Pod.Name))
+		observed, err = egressGatewayProbeSourceIP(ctx, t, &client, s.NoProxyTarget)
+		if err != nil {
+			a.Failf("Probing NO_PROXY target %s: %s", s.NoProxyTarget, err)
+			continue
+		}
+		if observed == gatewayIP {
+			a.Failf("NO_PROXY destination %s was SNATed to gateway IP %s, expected client Pod IP", s.NoProxyTarget, gatewayIP)
+		}
+	}
+}
+
+// egressGatewayFailover cordons the elected egress gateway node and asserts
+// that a second gateway, drawn from EgressGatewayNodes(), takes over within
+// FailoverTimeout.
+type egressGatewayFailover struct {
+	// Target is the external destination probed before and after failover.
+	Target string
+
+	// FailoverTimeout bounds how long to wait for a new gateway to take
+	// over after the elected one is cordoned. Defaults to 30s if zero.
+	FailoverTimeout time.Duration
+}
+
+func (egressGatewayFailover) Name() string {
+	return "egress-gateway-failover"
+}
+
+func (egressGatewayFailover) Requirements() []features.Requirement {
+	return []features.Requirement{features.RequireEnabled(features.EgressGateway)}
+}
+
+func (s egressGatewayFailover) Run(ctx context.Context, t *Test) {
+	timeout := s.FailoverTimeout
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+
+	electedGateway := t.EgressGatewayNode()
+	if electedGateway == "" {
+		t.Fatalf("Cannot find egress gateway node")
+	}
+	electedGatewayIP, err := egressGatewayNodeIP(ctx, t, electedGateway)
+	if err != nil {
+		t.Fatalf("Resolving elected gateway node %s address: %s", electedGateway, err)
+	}
+
+	candidates := t.EgressGatewayNodes()
+	if len(candidates) < 2 {
+		t.Fatalf("Failover test requires at least two egress gateway candidate nodes, got %d", len(candidates))
+	}
+	candidateIPs := make(map[string]struct{}, len(candidates))
+	for _, node := range candidates {
+		if node == electedGateway {
+			continue
+		}
+		ip, err := egressGatewayNodeIP(ctx, t, node)
+		if err != nil {
+			t.Fatalf("Resolving gateway candidate node %s address: %s", node, err)
+		}
+		candidateIPs[ip] = struct{}{}
+	}
+
+	client := t.ctx.clientPods[0]
+
+	a := t.NewGenericAction(s, "drain-elected-gateway")
+	if err := t.Context().Clients()[0].CordonNode(ctx, electedGateway); err != nil {
+		a.Failf("Cordoning elected gateway node %s: %s", electedGateway, err)
+		return
+	}
+	defer func() {
+		if err := t.Context().Clients()[0].UncordonNode(context.TODO(), electedGateway); err != nil {
+			t.Failf("Uncordoning gateway node %s: %s", electedGateway, err)
+		}
+	}()
+
+	a = t.NewGenericAction(s, "await-failover")
+	deadline := time.Now().Add(timeout)
+	var lastObserved string
+	var lastErr error
+	for time.Now().Before(deadline) {
+		lastObserved, lastErr = egressGatewayProbeSourceIP(ctx, t, &client, s.Target)
+		if _, ok := candidateIPs[lastObserved]; lastErr == nil && ok {
+			return
+		}
+		time.Sleep(time.Second)
+	}
+
+	if lastErr != nil {
+		a.Failf("No gateway took over from %s within %s: %s", electedGateway, timeout, lastErr)
+		return
+	}
+	a.Failf("No gateway took over from %s within %s, traffic still observed from %s", electedGateway, timeout, lastObserved)
+}
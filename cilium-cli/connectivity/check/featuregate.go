@@ -0,0 +1,325 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package check
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/cilium/cilium/cilium-cli/utils/features"
+)
+
+// featureExpr is a parsed boolean expression over Cilium feature state, as
+// produced by parseFeatureExpr for WithFeatureExpression. It mirrors the
+// --feature-gates string convention used by Kubernetes/cert-manager:
+// comma-separated `feature=true,feature=false` clauses, optionally combined
+// with `&&`, `||`, `!` and parentheses for anything more complex.
+type featureExpr interface {
+	// eval reports whether the expression is satisfied given the Test's
+	// current feature set. If not, it also returns a description of the
+	// specific clause that disqualified it, for use in skip reasons.
+	eval(t *Test) (bool, string)
+	String() string
+}
+
+// featureClause is a single `feature`, `feature=value` or `feature!=value`
+// test; `!feature` is parsed as a notExpr wrapping a clause. A bare `feature` or `feature=true`/`feature=false`
+// clause checks Status.Enabled; any other value is compared against
+// Status.Mode, e.g. `Tunnel!=disabled`.
+type featureClause struct {
+	feature  features.Feature
+	hasValue bool
+	notEqual bool
+	value    string
+}
+
+func (c *featureClause) eval(t *Test) (bool, string) {
+	status, ok := t.Context().Feature(c.feature)
+
+	var matched bool
+	switch {
+	case !c.hasValue:
+		matched = ok && status.Enabled
+	case c.value == "true" || c.value == "false":
+		matched = ok && status.Enabled == (c.value == "true")
+	default:
+		matched = ok && status.Mode == c.value
+	}
+	if c.notEqual {
+		matched = !matched
+	}
+
+	if matched {
+		return true, ""
+	}
+	return false, c.String()
+}
+
+func (c *featureClause) String() string {
+	if !c.hasValue {
+		return string(c.feature)
+	}
+	op := "="
+	if c.notEqual {
+		op = "!="
+	}
+	return fmt.Sprintf("%s%s%s", c.feature, op, c.value)
+}
+
+type notExpr struct {
+	x featureExpr
+}
+
+func (e *notExpr) eval(t *Test) (bool, string) {
+	if ok, _ := e.x.eval(t); !ok {
+		return true, ""
+	}
+	return false, e.String()
+}
+
+func (e *notExpr) String() string {
+	return "!" + e.x.String()
+}
+
+type andExpr struct {
+	l, r featureExpr
+}
+
+func (e *andExpr) eval(t *Test) (bool, string) {
+	if ok, reason := e.l.eval(t); !ok {
+		return false, reason
+	}
+	if ok, reason := e.r.eval(t); !ok {
+		return false, reason
+	}
+	return true, ""
+}
+
+func (e *andExpr) String() string {
+	return e.l.String() + " && " + e.r.String()
+}
+
+type orExpr struct {
+	l, r featureExpr
+}
+
+func (e *orExpr) eval(t *Test) (bool, string) {
+	if ok, _ := e.l.eval(t); ok {
+		return true, ""
+	}
+	if ok, _ := e.r.eval(t); ok {
+		return true, ""
+	}
+	return false, e.String()
+}
+
+func (e *orExpr) String() string {
+	return "(" + e.l.String() + " || " + e.r.String() + ")"
+}
+
+// featureExprToken is a single lexical token of a feature expression.
+type featureExprToken struct {
+	kind  string // "ident", "&&", "||", "!", "!=", "=", "(", ")", "eof"
+	value string
+}
+
+// lexFeatureExpr splits expr into tokens, treating ',' as equivalent to
+// '&&' so the Kubernetes/cert-manager-style comma-separated list form keeps
+// working alongside full boolean expressions.
+func lexFeatureExpr(expr string) ([]featureExprToken, error) {
+	var tokens []featureExprToken
+
+	runes := []rune(expr)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == ',':
+			tokens = append(tokens, featureExprToken{kind: "&&"})
+			i++
+		case r == '(' || r == ')':
+			tokens = append(tokens, featureExprToken{kind: string(r)})
+			i++
+		case r == '&' && i+1 < len(runes) && runes[i+1] == '&':
+			tokens = append(tokens, featureExprToken{kind: "&&"})
+			i += 2
+		case r == '|' && i+1 < len(runes) && runes[i+1] == '|':
+			tokens = append(tokens, featureExprToken{kind: "||"})
+			i += 2
+		case r == '!' && i+1 < len(runes) && runes[i+1] == '=':
+			tokens = append(tokens, featureExprToken{kind: "!="})
+			i += 2
+		case r == '!':
+			tokens = append(tokens, featureExprToken{kind: "!"})
+			i++
+		case r == '=':
+			tokens = append(tokens, featureExprToken{kind: "="})
+			i++
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_' || runes[i] == '-') {
+				i++
+			}
+			tokens = append(tokens, featureExprToken{kind: "ident", value: string(runes[start:i])})
+		default:
+			return nil, fmt.Errorf("unexpected character %q at offset %d", r, i)
+		}
+	}
+
+	tokens = append(tokens, featureExprToken{kind: "eof"})
+	return tokens, nil
+}
+
+// featureExprParser is a recursive-descent parser implementing, in order of
+// increasing precedence: '||', '&&', unary '!', and parenthesized or bare
+// clauses.
+type featureExprParser struct {
+	tokens []featureExprToken
+	pos    int
+}
+
+func (p *featureExprParser) peek() featureExprToken {
+	return p.tokens[p.pos]
+}
+
+func (p *featureExprParser) next() featureExprToken {
+	tok := p.tokens[p.pos]
+	p.pos++
+	return tok
+}
+
+func (p *featureExprParser) parseOr() (featureExpr, error) {
+	l, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == "||" {
+		p.next()
+		r, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l = &orExpr{l: l, r: r}
+	}
+	return l, nil
+}
+
+func (p *featureExprParser) parseAnd() (featureExpr, error) {
+	l, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == "&&" {
+		p.next()
+		r, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		l = &andExpr{l: l, r: r}
+	}
+	return l, nil
+}
+
+func (p *featureExprParser) parseUnary() (featureExpr, error) {
+	if p.peek().kind == "!" {
+		p.next()
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &notExpr{x: x}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *featureExprParser) parsePrimary() (featureExpr, error) {
+	tok := p.peek()
+	switch tok.kind {
+	case "(":
+		p.next()
+		x, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek().kind != ")" {
+			return nil, fmt.Errorf("expected ')', got %q", p.peek().kind)
+		}
+		p.next()
+		return x, nil
+	case "ident":
+		p.next()
+		clause := &featureClause{feature: features.Feature(tok.value)}
+		switch p.peek().kind {
+		case "=", "!=":
+			clause.notEqual = p.next().kind == "!="
+			clause.hasValue = true
+			value, err := p.expectIdent()
+			if err != nil {
+				return nil, err
+			}
+			clause.value = value
+		}
+		return clause, nil
+	default:
+		return nil, fmt.Errorf("expected feature name or '(', got %q", tok.kind)
+	}
+}
+
+func (p *featureExprParser) expectIdent() (string, error) {
+	tok := p.peek()
+	if tok.kind != "ident" {
+		return "", fmt.Errorf("expected feature value, got %q", tok.kind)
+	}
+	p.next()
+	return tok.value, nil
+}
+
+// parseFeatureExpr parses expr into a featureExpr. It fails on any syntax
+// error so WithFeatureExpression can fail fast at registration time.
+func parseFeatureExpr(expr string) (featureExpr, error) {
+	if strings.TrimSpace(expr) == "" {
+		return nil, fmt.Errorf("empty feature expression")
+	}
+
+	tokens, err := lexFeatureExpr(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &featureExprParser{tokens: tokens}
+	fe, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != "eof" {
+		return nil, fmt.Errorf("unexpected trailing token %q", p.peek().kind)
+	}
+	return fe, nil
+}
+
+// WithFeatureExpression parses expr as a boolean feature-gate expression and
+// adds it to the Test's requirements, evaluated in willRun() alongside
+// WithFeatureRequirements. Unlike WithFeatureRequirements, whose
+// Requirements are always AND-ed together, expr can combine `feature`,
+// `!feature`, `feature=value` and `feature!=value` clauses with `&&`, `||`,
+// `!`, parentheses, and comma-separated lists (commas behave as `&&`),
+// mirroring the --feature-gates convention used by Kubernetes/cert-manager.
+// For example:
+//
+//	EgressGateway && (IPv6 || Tunnel!=disabled)
+//
+// expr is parsed immediately so a malformed expression fails fast here,
+// rather than surfacing confusingly during willRun(). When the expression is
+// not met, the Test is skipped with the specific unmet clause named in the
+// skip reason.
+func (t *Test) WithFeatureExpression(expr string) *Test {
+	fe, err := parseFeatureExpr(expr)
+	if err != nil {
+		t.Fatalf("Parsing feature expression %q: %s", expr, err)
+	}
+	t.featureExprs = append(t.featureExprs, fe)
+	return t
+}
@@ -0,0 +1,96 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package check
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/cilium/cilium/cilium-cli/utils/features"
+)
+
+// HairpinServiceParams configures a hairpin-service Scenario: traffic from a
+// Pod to a Service whose only backend is that same Pod.
+type HairpinServiceParams struct {
+	// Pod is both the source of the traffic and the sole backend of the
+	// Service being exercised.
+	Pod *Pod
+
+	// ClusterIPPeer is the Service's ClusterIP, which Cilium can route
+	// directly to the backend Pod.
+	ClusterIPPeer TestPeer
+
+	// LoadBalancerPeer is the Service's external LoadBalancer/NodePort
+	// address. Unlike ClusterIPPeer, traffic through it is typically
+	// SNATed to a node or gateway address before looping back to the
+	// backend.
+	LoadBalancerPeer TestPeer
+}
+
+// hairpinService generates hairpin traffic (a Pod connecting to a Service
+// whose only backend is itself) and asserts that ingress NetworkPolicies
+// matching the Pod's own identity still permit the flow, since the source
+// will typically have been SNATed to a node/gateway address by the time it
+// loops back to the backend.
+type hairpinService struct {
+	params HairpinServiceParams
+}
+
+// NewHairpinServiceScenario builds a Scenario that connects params.Pod to a
+// Service whose only backend is params.Pod itself, covering both the
+// direct ClusterIP path and the SNATed LoadBalancer/NodePort path. This
+// closes a real gap where users writing identity-based ingress policies
+// silently break self-access through ClusterIP/LoadBalancer services.
+func (t *Test) NewHairpinServiceScenario(params HairpinServiceParams) Scenario {
+	return &hairpinService{params: params}
+}
+
+func (*hairpinService) Name() string {
+	return "hairpin-service"
+}
+
+func (s *hairpinService) Run(ctx context.Context, t *Test) {
+	t.NewAction(s, "direct-backend", s.params.Pod, s.params.ClusterIPPeer, features.IPFamilyAny).Run(func(a *Action) {
+		a.ExecInPod(ctx, a.CurlCommand(s.params.ClusterIPPeer))
+	})
+	t.NewAction(s, "load-balanced", s.params.Pod, s.params.LoadBalancerPeer, features.IPFamilyAny).Run(func(a *Action) {
+		a.ExecInPod(ctx, a.CurlCommand(s.params.LoadBalancerPeer))
+	})
+
+	if !t.HasNetworkPolicies() {
+		return
+	}
+
+	a := t.NewGenericAction(s, "verify-no-policy-denies")
+	if err := s.checkNoPolicyDenies(ctx, t); err != nil {
+		a.Failf("Hairpin traffic was denied by a NetworkPolicy: %s", err)
+	}
+}
+
+// checkNoPolicyDenies scrapes Hubble for dropped flows involving the Pod (as
+// either source or destination, not only the hairpin 5-tuple) and fails if
+// any of them mentions "policy-denied", to catch a policy that
+// correctly permits external traffic but silently breaks Pod-to-self access
+// through a Service.
+func (s *hairpinService) checkNoPolicyDenies(ctx context.Context, t *Test) error {
+	pod := s.params.Pod
+
+	for _, client := range t.Context().Clients() {
+		out, err := client.ExecInPod(ctx, "kube-system", "hubble-relay", "hubble-relay", []string{
+			"hubble", "observe",
+			"--verdict", "DROPPED",
+			"--pod", fmt.Sprintf("%s/%s", pod.Pod.Namespace, pod.Pod.Name),
+			"--output", "compact",
+		})
+		if err != nil {
+			return fmt.Errorf("querying Hubble for policy verdicts: %w", err)
+		}
+		if strings.Contains(out.String(), "policy-denied") {
+			return fmt.Errorf("found a policy-denied verdict for %s/%s: %s", pod.Pod.Namespace, pod.Pod.Name, out.String())
+		}
+	}
+
+	return nil
+}
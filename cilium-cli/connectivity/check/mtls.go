@@ -0,0 +1,307 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package check
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cloudflare/cfssl/cli/genkey"
+	"github.com/cloudflare/cfssl/config"
+	"github.com/cloudflare/cfssl/csr"
+	"github.com/cloudflare/cfssl/helpers"
+	"github.com/cloudflare/cfssl/initca"
+	"github.com/cloudflare/cfssl/signer"
+	"github.com/cloudflare/cfssl/signer/local"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/cilium/cilium/cilium-cli/utils/features"
+)
+
+// WithClientCertificate mints a CA and a client-auth certificate signed by
+// it (CN set to name, with sans as additional DNS/IP SANs), following the
+// same self-signed flow as WithCertificate but with a "client auth" key
+// usage rather than "server auth". The resulting keypair is stored in a
+// Secret named name, and the issuing CA is registered under name in
+// CertificateCAs/CertificateKeys for use by WithMutualTLSServer and CNPs
+// using terminatingTLS/originatingTLS.
+func (t *Test) WithClientCertificate(name string, sans ...string) *Test {
+	caCert, _, caKey, err := initca.New(&csr.CertificateRequest{
+		KeyRequest: csr.NewKeyRequest(),
+		CN:         fmt.Sprintf("%s Test CA", name),
+	})
+	if err != nil {
+		t.Fatalf("Unable to create CA for client certificate %q: %s", name, err)
+	}
+
+	g := &csr.Generator{Validator: genkey.Validator}
+	csrBytes, keyBytes, err := g.ProcessRequest(&csr.CertificateRequest{
+		CN:    name,
+		Hosts: sans,
+	})
+	if err != nil {
+		t.Fatalf("Unable to create CSR for client certificate %q: %s", name, err)
+	}
+
+	parsedCa, err := helpers.ParseCertificatePEM(caCert)
+	if err != nil {
+		t.Fatalf("Unable to parse CA for client certificate %q: %s", name, err)
+	}
+	caPriv, err := helpers.ParsePrivateKeyPEM(caKey)
+	if err != nil {
+		t.Fatalf("Unable to parse CA key for client certificate %q: %s", name, err)
+	}
+
+	signConf := &config.Signing{
+		Default: &config.SigningProfile{
+			Expiry: 365 * 24 * time.Hour,
+			Usage:  []string{"key encipherment", "client auth", "digital signature"},
+		},
+	}
+
+	s, err := local.NewSigner(caPriv, parsedCa, signer.DefaultSigAlgo(caPriv), signConf)
+	if err != nil {
+		t.Fatalf("Unable to create signer for client certificate %q: %s", name, err)
+	}
+	certBytes, err := s.Sign(signer.SignRequest{Request: string(csrBytes)})
+	if err != nil {
+		t.Fatalf("Unable to sign client certificate %q: %s", name, err)
+	}
+
+	if t.certificateCAs == nil {
+		t.certificateCAs = make(map[string][]byte)
+	}
+	t.certificateCAs[name] = caCert
+
+	if t.certificateKeys == nil {
+		t.certificateKeys = make(map[string][]byte)
+	}
+	t.certificateKeys[name] = caKey
+
+	return t.WithSecret(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+		Type: corev1.SecretTypeTLS,
+		Data: map[string][]byte{
+			corev1.TLSCertKey:       certBytes,
+			corev1.TLSPrivateKeyKey: keyBytes,
+		},
+	})
+}
+
+// WithMutualTLSServerParams configures the external TLS server deployed by
+// WithMutualTLSServer.
+type WithMutualTLSServerParams struct {
+	// Name names both the server's TLS Secret and the CA bundle Secret
+	// used to verify client certificates.
+	Name string
+
+	// Hostname is the DNS SAN requested for the server's certificate.
+	Hostname string
+
+	// TrustedClientCAs lists the names previously registered via
+	// WithClientCertificate (or WithCertificate) whose CAs the server
+	// should accept client certificates from.
+	TrustedClientCAs []string
+}
+
+// WithMutualTLSServer mints a server certificate, bundles the CAs named in
+// TrustedClientCAs into a client-CA trust anchor, and stores both as
+// Secrets for an external TLS server (deployed on a NodesWithoutCilium()
+// node) that requires and verifies client certificates against that trust
+// anchor. The Secrets are named Name (server keypair), Name+"-client-ca"
+// (trust anchor) and Name+"-server-ca" (the server certificate's issuing
+// CA). It only creates these Secrets; it does not deploy the server itself.
+func (t *Test) WithMutualTLSServer(params WithMutualTLSServerParams) *Test {
+	caCert, _, caKey, err := initca.New(&csr.CertificateRequest{
+		KeyRequest: csr.NewKeyRequest(),
+		CN:         fmt.Sprintf("%s Server CA", params.Name),
+	})
+	if err != nil {
+		t.Fatalf("Unable to create server CA for mTLS server %q: %s", params.Name, err)
+	}
+
+	g := &csr.Generator{Validator: genkey.Validator}
+	csrBytes, keyBytes, err := g.ProcessRequest(&csr.CertificateRequest{
+		CN:    params.Hostname,
+		Hosts: []string{params.Hostname},
+	})
+	if err != nil {
+		t.Fatalf("Unable to create CSR for mTLS server %q: %s", params.Name, err)
+	}
+
+	parsedCa, err := helpers.ParseCertificatePEM(caCert)
+	if err != nil {
+		t.Fatalf("Unable to parse server CA for mTLS server %q: %s", params.Name, err)
+	}
+	caPriv, err := helpers.ParsePrivateKeyPEM(caKey)
+	if err != nil {
+		t.Fatalf("Unable to parse server CA key for mTLS server %q: %s", params.Name, err)
+	}
+
+	signConf := &config.Signing{
+		Default: &config.SigningProfile{
+			Expiry: 365 * 24 * time.Hour,
+			Usage:  []string{"key encipherment", "server auth", "digital signature"},
+		},
+	}
+
+	s, err := local.NewSigner(caPriv, parsedCa, signer.DefaultSigAlgo(caPriv), signConf)
+	if err != nil {
+		t.Fatalf("Unable to create signer for mTLS server %q: %s", params.Name, err)
+	}
+	certBytes, err := s.Sign(signer.SignRequest{Request: string(csrBytes)})
+	if err != nil {
+		t.Fatalf("Unable to sign mTLS server certificate %q: %s", params.Name, err)
+	}
+
+	t.WithSecret(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: params.Name},
+		Type:       corev1.SecretTypeTLS,
+		Data: map[string][]byte{
+			corev1.TLSCertKey:       certBytes,
+			corev1.TLSPrivateKeyKey: keyBytes,
+		},
+	})
+
+	clientCABundle := make([]byte, 0)
+	for _, name := range params.TrustedClientCAs {
+		ca, ok := t.certificateCAs[name]
+		if !ok {
+			t.Fatalf("Unknown client CA %q for mTLS server %q", name, params.Name)
+		}
+		clientCABundle = append(clientCABundle, ca...)
+	}
+
+	t.WithSecret(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: params.Name + "-client-ca"},
+		Data: map[string][]byte{
+			"ca.crt": clientCABundle,
+		},
+	})
+
+	// The server cert above is self-signed by caCert, but that CA isn't
+	// trusted by anything yet: stash it in its own Secret so a client can
+	// mount it and pass it to curl via --cacert, independently of whichever
+	// client certificate it's presenting.
+	t.WithSecret(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: params.Name + "-server-ca"},
+		Data: map[string][]byte{
+			"ca.crt": caCert,
+		},
+	})
+
+	return t
+}
+
+// MutualTLSEgressParams configures the mutualTLSEgress Scenario built by
+// NewMutualTLSEgressScenario.
+type MutualTLSEgressParams struct {
+	// Client is the Pod presenting the client certificate.
+	Client *Pod
+
+	// Server is the external mTLS server's address.
+	Server string
+
+	// ServerCASecret is the name of the Secret holding Server's self-signed
+	// CA (the Name+"-server-ca" Secret created by WithMutualTLSServer).
+	// curl needs this to verify Server's certificate independently of
+	// whichever client certificate is under test, so that a TLS failure in
+	// the missing/untrusted-client-cert cases can only be caused by the
+	// server rejecting the client certificate, not by curl failing to
+	// verify the server.
+	ServerCASecret string
+
+	// ClientCertSecret is the name of the Secret holding the correct
+	// client keypair, as created by WithClientCertificate.
+	ClientCertSecret string
+
+	// UnrelatedCertSecret is the name of a Secret holding a client
+	// keypair signed by a different, untrusted CA.
+	UnrelatedCertSecret string
+
+	// TLSInterceptionExpected indicates a CiliumNetworkPolicy with
+	// terminatingTLS/originatingTLS is in scope for this traffic, meaning
+	// the proxy re-originates mTLS to Server rather than the client
+	// connecting to it directly.
+	TLSInterceptionExpected bool
+}
+
+// mutualTLSEgress exercises a client cert minted by WithClientCertificate
+// against an external TLS server set up by WithMutualTLSServer: a request
+// presenting the right client cert must succeed, a request with no cert or
+// with a cert signed by an unrelated CA must fail the TLS handshake, and
+// when a CiliumNetworkPolicy intercepting the connection (terminatingTLS/
+// originatingTLS) is in scope, the proxy must correctly re-originate mTLS to
+// the upstream.
+type mutualTLSEgress struct {
+	MutualTLSEgressParams
+}
+
+// NewMutualTLSEgressScenario builds a Scenario exercising mTLS egress from
+// params.Client to params.Server using the certificates provisioned by
+// WithClientCertificate/WithMutualTLSServer.
+func (t *Test) NewMutualTLSEgressScenario(params MutualTLSEgressParams) Scenario {
+	return &mutualTLSEgress{params}
+}
+
+func (*mutualTLSEgress) Name() string {
+	return "mutual-tls-egress"
+}
+
+func (s *mutualTLSEgress) Requirements() []features.Requirement {
+	if s.TLSInterceptionExpected {
+		return []features.Requirement{features.RequireEnabled(features.TLSPolicy)}
+	}
+	return nil
+}
+
+func (s *mutualTLSEgress) Run(ctx context.Context, t *Test) {
+	a := t.NewGenericAction(s, "valid-client-cert")
+	if err := s.curlWithCert(ctx, t, s.ClientCertSecret); err != nil {
+		a.Failf("Request with valid client certificate failed: %s", err)
+	}
+
+	a = t.NewGenericAction(s, "missing-client-cert")
+	if err := s.curlWithCert(ctx, t, ""); err == nil {
+		a.Failf("Request without a client certificate unexpectedly succeeded")
+	}
+
+	a = t.NewGenericAction(s, "untrusted-client-cert")
+	if err := s.curlWithCert(ctx, t, s.UnrelatedCertSecret); err == nil {
+		a.Failf("Request with a client certificate signed by an unrelated CA unexpectedly succeeded")
+	}
+
+	if s.TLSInterceptionExpected {
+		a = t.NewGenericAction(s, "proxy-reoriginates-mtls")
+		if err := s.curlWithCert(ctx, t, s.ClientCertSecret); err != nil {
+			a.Failf("Request through TLS-intercepting CiliumNetworkPolicy failed: %s", err)
+		}
+	}
+}
+
+// curlWithCert execs a curl from s.Client against s.Server, presenting the
+// client certificate stored in secretName (the empty string omits
+// --cert/--key entirely, for the no-cert case).
+func (s *mutualTLSEgress) curlWithCert(ctx context.Context, t *Test, secretName string) error {
+	cmd := []string{"curl", "--silent", "--fail", "--max-time", "5",
+		"--cacert", fmt.Sprintf("/certs/%s/ca.crt", s.ServerCASecret)}
+	if secretName != "" {
+		cmd = append(cmd,
+			"--cert", fmt.Sprintf("/certs/%s/tls.crt", secretName),
+			"--key", fmt.Sprintf("/certs/%s/tls.key", secretName),
+		)
+	}
+	cmd = append(cmd, fmt.Sprintf("https://%s", s.Server))
+
+	out, err := t.Context().Clients()[0].ExecInPod(ctx, s.Client.Pod.Namespace, s.Client.Pod.Name, s.Client.Pod.Labels["name"], cmd)
+	if err != nil {
+		return fmt.Errorf("%w (%s)", err, strings.TrimSpace(out.String()))
+	}
+	return nil
+}
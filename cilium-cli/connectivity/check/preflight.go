@@ -0,0 +1,279 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package check
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Severity controls how a failing Checker affects the Test it guards.
+type Severity int
+
+const (
+	// SeverityFatal skips the Test, using the Checker's error as the skip
+	// reason.
+	SeverityFatal Severity = iota
+	// SeverityWarn logs the Checker's error but lets the Test proceed.
+	SeverityWarn
+)
+
+func (s Severity) String() string {
+	if s == SeverityWarn {
+		return "warn"
+	}
+	return "fatal"
+}
+
+// Checker is a fast sanity check run before a Test's setup(), analogous to
+// kubeadm's preflight checks: it's meant to catch a missing prerequisite (an
+// unloaded kernel module, an unreachable apiserver, a stale CRD) with a
+// clear, structured reason, rather than letting the Test fail deep inside
+// setup() or a Scenario. Register one against every Test via
+// RegisterChecker, or against a single Test via Test.WithPreflight.
+type Checker interface {
+	// Name identifies the Checker in skip/warning messages.
+	Name() string
+	// Check runs the check, returning a non-nil error describing what's
+	// missing or broken if it fails.
+	Check(ctx context.Context, t *Test) error
+	// Severity determines whether a failing Check skips the Test
+	// (SeverityFatal) or only logs a warning and lets it proceed
+	// (SeverityWarn).
+	Severity() Severity
+}
+
+// defaultCheckers is the global registry of Checkers applied to every Test,
+// in addition to whatever it registers for itself via WithPreflight.
+// Populated by RegisterChecker, normally from an init() alongside the
+// Checker it registers.
+var defaultCheckers []Checker
+
+// RegisterChecker adds c to the global registry of Checkers run before every
+// Test's setup, in registration order. Scenarios and other packages can call
+// this from an init() to declare an additional preflight requirement without
+// every Test having to opt in individually.
+func RegisterChecker(c Checker) {
+	defaultCheckers = append(defaultCheckers, c)
+}
+
+func init() {
+	RegisterChecker(kernelModulesChecker{Modules: []string{"vxlan", "iptable_nat", "iptable_mangle", "iptable_raw"}})
+	RegisterChecker(mtuConsistencyChecker{})
+	RegisterChecker(apiserverReachabilityChecker{})
+	RegisterChecker(corednsReadinessChecker{})
+	RegisterChecker(ciliumCRDVersionChecker{RequiredCRDs: []string{
+		"ciliumnetworkpolicies.cilium.io",
+		"ciliumegressgatewaypolicies.cilium.io",
+	}})
+	RegisterChecker(clockSkewChecker{MaxSkew: 5 * time.Second})
+}
+
+// WithPreflight registers one or more Checkers to run before this Test's
+// setup, in addition to the ones in the global registry. A failing
+// SeverityFatal Checker skips the Test with its error as the skip reason,
+// instead of letting the Test run and fail deep inside setup() or a
+// Scenario; a failing SeverityWarn Checker only logs a warning.
+func (t *Test) WithPreflight(checkers ...Checker) *Test {
+	t.preflightChecks = append(t.preflightChecks, checkers...)
+	return t
+}
+
+// runPreflightChecks runs every Checker in the global registry followed by
+// this Test's own (registered via WithPreflight), in order, unless the user
+// passed --skip-preflight. It stops at the first SeverityFatal failure and
+// returns a skip reason derived from it; a SeverityWarn failure is logged
+// and does not stop the run. It returns the empty string if no fatal check
+// failed.
+func (t *Test) runPreflightChecks(ctx context.Context) string {
+	if t.ctx.params.SkipPreflight {
+		return ""
+	}
+
+	checkers := make([]Checker, 0, len(defaultCheckers)+len(t.preflightChecks))
+	checkers = append(checkers, defaultCheckers...)
+	checkers = append(checkers, t.preflightChecks...)
+
+	for _, c := range checkers {
+		if err := c.Check(ctx, t); err != nil {
+			if c.Severity() == SeverityWarn {
+				t.Logf("[!] Preflight check %q warned: %s", c.Name(), err)
+				continue
+			}
+			return fmt.Sprintf("preflight check %q failed: %s", c.Name(), err)
+		}
+	}
+	return ""
+}
+
+// kernelModulesChecker verifies that every required kernel module is loaded
+// on the node backing each Cilium agent Pod.
+type kernelModulesChecker struct {
+	Modules []string
+}
+
+func (kernelModulesChecker) Name() string       { return "kernel-modules" }
+func (kernelModulesChecker) Severity() Severity { return SeverityFatal }
+
+func (c kernelModulesChecker) Check(ctx context.Context, t *Test) error {
+	for _, pod := range t.Context().CiliumPods() {
+		out, err := t.Context().Clients()[0].ExecInPod(ctx, pod.Pod.Namespace, pod.Pod.Name, pod.Pod.Labels["name"],
+			[]string{"cat", "/proc/modules"})
+		if err != nil {
+			return fmt.Errorf("reading loaded kernel modules on node %s: %w", pod.Pod.Spec.NodeName, err)
+		}
+		loaded := out.String()
+		for _, m := range c.Modules {
+			if !strings.Contains(loaded, m+" ") {
+				return fmt.Errorf("required kernel module %q is not loaded on node %s", m, pod.Pod.Spec.NodeName)
+			}
+		}
+	}
+	return nil
+}
+
+// mtuConsistencyChecker verifies that every client Pod reports the same eth0
+// MTU across the nodes they run on, since a mismatch silently causes PMTU
+// blackholes rather than a clean failure.
+type mtuConsistencyChecker struct{}
+
+func (mtuConsistencyChecker) Name() string       { return "mtu-consistency" }
+func (mtuConsistencyChecker) Severity() Severity { return SeverityWarn }
+
+func (mtuConsistencyChecker) Check(ctx context.Context, t *Test) error {
+	var want, wantNode string
+	for _, pod := range t.ctx.clientPods {
+		out, err := t.Context().Clients()[0].ExecInPod(ctx, pod.Pod.Namespace, pod.Pod.Name, pod.Pod.Labels["name"],
+			[]string{"cat", "/sys/class/net/eth0/mtu"})
+		if err != nil {
+			return fmt.Errorf("reading MTU on node %s: %w", pod.Pod.Spec.NodeName, err)
+		}
+		mtu := strings.TrimSpace(out.String())
+		if want == "" {
+			want, wantNode = mtu, pod.Pod.Spec.NodeName
+			continue
+		}
+		if mtu != want {
+			return fmt.Errorf("node %s reports eth0 MTU %s, but node %s reports %s", pod.Pod.Spec.NodeName, mtu, wantNode, want)
+		}
+	}
+	return nil
+}
+
+// apiserverReachabilityChecker verifies that a test Pod can reach the
+// Kubernetes apiserver, so a later test failure can't be mistaken for a
+// NetworkPolicy or routing bug when the real cause is apiserver
+// connectivity.
+type apiserverReachabilityChecker struct{}
+
+func (apiserverReachabilityChecker) Name() string       { return "apiserver-reachability" }
+func (apiserverReachabilityChecker) Severity() Severity { return SeverityFatal }
+
+func (apiserverReachabilityChecker) Check(ctx context.Context, t *Test) error {
+	if len(t.ctx.clientPods) == 0 {
+		return nil
+	}
+	pod := t.ctx.clientPods[0]
+	cmd := []string{"curl", "--silent", "--max-time", "5", "--insecure",
+		"-o", "/dev/null", "-w", "%{http_code}", "https://kubernetes.default.svc/healthz"}
+	out, err := t.Context().Clients()[0].ExecInPod(ctx, pod.Pod.Namespace, pod.Pod.Name, pod.Pod.Labels["name"], cmd)
+	if err != nil {
+		return fmt.Errorf("reaching apiserver from pod %s: %w", pod.Pod.Name, err)
+	}
+	if strings.TrimSpace(out.String()) == "" {
+		return fmt.Errorf("apiserver did not respond to a healthz probe from pod %s", pod.Pod.Name)
+	}
+	return nil
+}
+
+// corednsReadinessChecker verifies that CoreDNS has at least one ready
+// replica, since most connectivity Scenarios resolve a peer by name before
+// connecting to it.
+type corednsReadinessChecker struct{}
+
+func (corednsReadinessChecker) Name() string       { return "coredns-readiness" }
+func (corednsReadinessChecker) Severity() Severity { return SeverityFatal }
+
+func (corednsReadinessChecker) Check(ctx context.Context, t *Test) error {
+	for _, client := range t.Context().Clients() {
+		dep, err := client.GetDeployment(ctx, "kube-system", "coredns", metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("getting CoreDNS Deployment: %w", err)
+		}
+		if dep.Status.ReadyReplicas == 0 {
+			return fmt.Errorf("CoreDNS Deployment kube-system/coredns has no ready replicas")
+		}
+	}
+	return nil
+}
+
+// ciliumCRDVersionChecker verifies that every CRD Cilium's own policy
+// objects (CiliumNetworkPolicy, CiliumEgressGatewayPolicy, ...) depend on is
+// installed with at least one served version, catching a partially-applied
+// or stale CRD manifest before a Test fails with an opaque apiserver 404.
+type ciliumCRDVersionChecker struct {
+	RequiredCRDs []string
+}
+
+func (ciliumCRDVersionChecker) Name() string       { return "cilium-crd-versions" }
+func (ciliumCRDVersionChecker) Severity() Severity { return SeverityFatal }
+
+func (c ciliumCRDVersionChecker) Check(ctx context.Context, t *Test) error {
+	for _, client := range t.Context().Clients() {
+		for _, name := range c.RequiredCRDs {
+			crd, err := client.GetCRD(ctx, name, metav1.GetOptions{})
+			if err != nil {
+				return fmt.Errorf("getting CRD %s: %w", name, err)
+			}
+			servedVersion := false
+			for _, v := range crd.Spec.Versions {
+				if v.Served {
+					servedVersion = true
+					break
+				}
+			}
+			if !servedVersion {
+				return fmt.Errorf("CRD %s has no served version", name)
+			}
+		}
+	}
+	return nil
+}
+
+// clockSkewChecker verifies that each node's clock isn't skewed far enough
+// from the control machine's to throw off TTL/expiry-based assertions (e.g.
+// certificate validity windows, Hubble flow timestamps).
+type clockSkewChecker struct {
+	MaxSkew time.Duration
+}
+
+func (clockSkewChecker) Name() string       { return "clock-skew" }
+func (clockSkewChecker) Severity() Severity { return SeverityWarn }
+
+func (c clockSkewChecker) Check(ctx context.Context, t *Test) error {
+	for _, pod := range t.ctx.clientPods {
+		out, err := t.Context().Clients()[0].ExecInPod(ctx, pod.Pod.Namespace, pod.Pod.Name, pod.Pod.Labels["name"],
+			[]string{"date", "+%s"})
+		if err != nil {
+			return fmt.Errorf("reading clock on node %s: %w", pod.Pod.Spec.NodeName, err)
+		}
+		remoteUnix, err := strconv.ParseInt(strings.TrimSpace(out.String()), 10, 64)
+		if err != nil {
+			return fmt.Errorf("parsing clock reading from node %s: %w", pod.Pod.Spec.NodeName, err)
+		}
+		skew := time.Since(time.Unix(remoteUnix, 0))
+		if skew < 0 {
+			skew = -skew
+		}
+		if skew > c.MaxSkew {
+			return fmt.Errorf("node %s clock is skewed by %s, exceeding the %s threshold", pod.Pod.Spec.NodeName, skew, c.MaxSkew)
+		}
+	}
+	return nil
+}
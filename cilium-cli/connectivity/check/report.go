@@ -0,0 +1,259 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package check
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// scenarioTiming records when a Scenario started and finished executing, as
+// observed by Test.Run.
+type scenarioTiming struct {
+	start time.Time
+	end   time.Time
+}
+
+func (s scenarioTiming) duration() time.Duration {
+	return s.end.Sub(s.start)
+}
+
+// ScenarioReport is the structured, per-scenario result of a Test run,
+// suitable for serialization to JSON or JUnit XML.
+type ScenarioReport struct {
+	Name            string        `json:"name"`
+	Skipped         bool          `json:"skipped"`
+	SkipReason      string        `json:"skipReason,omitempty"`
+	Failed          bool          `json:"failed"`
+	FailureMessages []string      `json:"failureMessages,omitempty"`
+	StartTime       time.Time     `json:"startTime,omitempty"`
+	Duration        time.Duration `json:"durationNanoseconds,omitempty"`
+}
+
+// TestReport is the structured result of a Test run, suitable for
+// serialization to JSON or JUnit XML.
+type TestReport struct {
+	Name       string           `json:"name"`
+	Skipped    bool             `json:"skipped"`
+	SkipReason string           `json:"skipReason,omitempty"`
+	Failed     bool             `json:"failed"`
+	StartTime  time.Time        `json:"startTime,omitempty"`
+	Duration   time.Duration    `json:"durationNanoseconds,omitempty"`
+	Scenarios  []ScenarioReport `json:"scenarios,omitempty"`
+}
+
+// Report builds the structured result of this Test, including per-scenario
+// timing for every Scenario that actually ran.
+func (t *Test) Report() TestReport {
+	report := TestReport{
+		Name:      t.Name(),
+		Failed:    t.Failed(),
+		StartTime: t.startTime,
+		Duration:  t.completionTime.Sub(t.startTime),
+	}
+
+	switch {
+	case t.skipped:
+		report.Skipped = true
+		report.SkipReason = t.skipReason
+	case len(t.scenarios) > 0 && len(t.scenarios) == len(t.scenariosSkipped):
+		report.Skipped = true
+	}
+
+	// Iterate scenarioOrder, not the scenarios map, so the report's Scenario
+	// order is deterministic regardless of what order Scenarios actually
+	// completed in under WithParallelism.
+	for _, s := range t.scenarioOrder {
+		sr := ScenarioReport{Name: s.Name()}
+
+		if timing, ran := t.scenarioTimings[s]; ran {
+			sr.StartTime = timing.start
+			sr.Duration = timing.duration()
+		} else {
+			sr.Skipped = true
+		}
+
+		for _, a := range t.scenarios[s] {
+			if a.failed {
+				sr.Failed = true
+				if a.failureMessage != "" {
+					sr.FailureMessages = append(sr.FailureMessages, a.failureMessage)
+				}
+			}
+		}
+
+		report.Scenarios = append(report.Scenarios, sr)
+	}
+
+	return report
+}
+
+// WriteJSONReport encodes reports as a JSON array to w.
+func WriteJSONReport(w io.Writer, reports []TestReport) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(reports)
+}
+
+// Reporter is notified once a Test has finished running, whether it passed,
+// failed or was skipped. Test.Run calls Report for every registered Reporter
+// after the Test's finalizers have executed, and the caller driving the
+// overall connectivity run is expected to call Close once every Test has
+// been reported, to flush any buffered output to disk.
+//
+// Reporter exists so CI systems (Prow, GitHub Actions test summaries, ...)
+// can consume a connectivity run's results as a JUnit XML or NDJSON artifact
+// instead of having to scrape stdout.
+type Reporter interface {
+	// Report is called with the structured result of a single Test.
+	Report(r TestReport) error
+
+	// Close flushes any buffered output and closes the underlying writer.
+	// It is called once after every Test has been reported.
+	Close() error
+}
+
+// junitReporter buffers TestReports in memory and renders them as a single
+// JUnit XML document on Close, since the JUnit schema requires the total
+// test/failure/skip counts up front in the <testsuite> element.
+type junitReporter struct {
+	w       io.WriteCloser
+	reports []TestReport
+}
+
+// NewJUnitReporter returns a Reporter that writes a JUnit XML document to w
+// on Close.
+func NewJUnitReporter(w io.WriteCloser) Reporter {
+	return &junitReporter{w: w}
+}
+
+func (j *junitReporter) Report(r TestReport) error {
+	j.reports = append(j.reports, r)
+	return nil
+}
+
+func (j *junitReporter) Close() error {
+	defer j.w.Close()
+	return WriteJUnitReport(j.w, j.reports)
+}
+
+// ndjsonReporter writes one JSON-encoded TestReport per line as Tests
+// complete, rather than buffering the whole run, so a tailing CI dashboard
+// can pick up results incrementally.
+type ndjsonReporter struct {
+	w   io.WriteCloser
+	enc *json.Encoder
+}
+
+// NewNDJSONReporter returns a Reporter that appends one JSON object per line
+// to w as each Test is reported.
+func NewNDJSONReporter(w io.WriteCloser) Reporter {
+	return &ndjsonReporter{w: w, enc: json.NewEncoder(w)}
+}
+
+func (n *ndjsonReporter) Report(r TestReport) error {
+	return n.enc.Encode(r)
+}
+
+func (n *ndjsonReporter) Close() error {
+	return n.w.Close()
+}
+
+// NewResultDirReporters creates "junit.xml" and "results.ndjson" in dir and
+// returns Reporters writing to each, for use with the connectivity command's
+// --result-dir flag. Callers must Close every returned Reporter once the run
+// has completed to ensure the JUnit document is flushed.
+func NewResultDirReporters(dir string) ([]Reporter, error) {
+	junitFile, err := os.Create(filepath.Join(dir, "junit.xml"))
+	if err != nil {
+		return nil, fmt.Errorf("creating JUnit report file: %w", err)
+	}
+
+	ndjsonFile, err := os.Create(filepath.Join(dir, "results.ndjson"))
+	if err != nil {
+		junitFile.Close()
+		return nil, fmt.Errorf("creating NDJSON report file: %w", err)
+	}
+
+	return []Reporter{NewJUnitReporter(junitFile), NewNDJSONReporter(ndjsonFile)}, nil
+}
+
+// junitTestSuites, junitTestSuite and junitTestCase model just enough of the
+// JUnit XML schema for CI systems to render pass/fail/skip status and timing
+// per Test/Scenario.
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      float64         `xml:"time,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string         `xml:"name,attr"`
+	ClassName string         `xml:"classname,attr"`
+	Time      float64        `xml:"time,attr"`
+	Failure   *junitFailure  `xml:"failure,omitempty"`
+	Skipped   *junitSkipped  `xml:"skipped,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr,omitempty"`
+}
+
+// WriteJUnitReport encodes reports as a JUnit XML document to w, with one
+// testsuite per Test and one testcase per Scenario.
+func WriteJUnitReport(w io.Writer, reports []TestReport) error {
+	suites := junitTestSuites{}
+
+	for _, r := range reports {
+		suite := junitTestSuite{
+			Name: r.Name,
+			Time: r.Duration.Seconds(),
+		}
+
+		for _, s := range r.Scenarios {
+			suite.Tests++
+			tc := junitTestCase{
+				Name:      s.Name,
+				ClassName: r.Name,
+				Time:      s.Duration.Seconds(),
+			}
+			switch {
+			case s.Skipped:
+				suite.Skipped++
+				tc.Skipped = &junitSkipped{Message: s.SkipReason}
+			case s.Failed:
+				suite.Failures++
+				msg := ""
+				if len(s.FailureMessages) > 0 {
+					msg = s.FailureMessages[0]
+				}
+				tc.Failure = &junitFailure{Message: msg}
+			}
+			suite.TestCases = append(suite.TestCases, tc)
+		}
+
+		suites.Suites = append(suites.Suites, suite)
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	return enc.Encode(suites)
+}
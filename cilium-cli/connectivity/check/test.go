@@ -11,7 +11,10 @@ import (
 	"fmt"
 	"io"
 	"net"
+	"runtime/debug"
 	"slices"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/blang/semver/v4"
@@ -63,13 +66,14 @@ func NewTest(name string, verbose bool, debug bool) *Test {
 		panic("empty test name")
 	}
 	test := &Test{
-		name:        name,
-		scenarios:   make(map[Scenario][]*Action),
-		resources:   []k8s.Object{},
-		clrps:       make(map[string]*ciliumv2.CiliumLocalRedirectPolicy),
-		logBuf:      &bytes.Buffer{}, // maintain internal buffer by default
-		conditionFn: nil,
-		verbose:     verbose,
+		name:            name,
+		scenarios:       make(map[Scenario][]*Action),
+		scenarioTimings: make(map[Scenario]scenarioTiming),
+		resources:       []k8s.Object{},
+		clrps:           make(map[string]*ciliumv2.CiliumLocalRedirectPolicy),
+		logBuf:          &bytes.Buffer{}, // maintain internal buffer by default
+		conditionFn:     nil,
+		verbose:         verbose,
 	}
 	// Setting the internal buffer to nil causes the logger to
 	// write directly to stdout in verbose or debug mode.
@@ -89,6 +93,12 @@ type Test struct {
 	// True if the Test is marked as skipped.
 	skipped bool
 
+	// Reason the Test was skipped, if skipped is true. Populated alongside
+	// skipped so Report() can surface it without re-deriving it from
+	// willRun()/runPreflightChecks(), which are not safe to call again
+	// after Run() has returned.
+	skipReason string
+
 	// True if the Test is marked as failed.
 	failed bool
 
@@ -96,6 +106,10 @@ type Test struct {
 	// for this test to be run
 	requirements []features.Requirement
 
+	// featureExprs are boolean feature-gate expressions registered via
+	// WithFeatureExpression, evaluated in willRun() alongside requirements.
+	featureExprs []featureExpr
+
 	// installIPRoutesFromOutsideToPodCIDRs indicates that the test runner needs
 	// to install podCIDR => nodeIP routes before running the test
 	installIPRoutesFromOutsideToPodCIDRs bool
@@ -107,10 +121,40 @@ type Test struct {
 	// Scenarios registered to this test.
 	scenarios map[Scenario][]*Action
 
+	// scenarioOrder records the order Scenarios were registered in via
+	// WithScenarios, independently of map iteration order, so Report() (and
+	// the JUnit/NDJSON Reporters built on it) can produce a deterministic
+	// Scenario ordering regardless of what order Scenarios actually
+	// completed in under WithParallelism.
+	scenarioOrder []Scenario
+
 	// Scenarios marked as skipped during execution.
 	// Needs to be stored as a list, these are implemented in another package.
 	scenariosSkipped []Scenario
 
+	// scenarioTimings records the start/end time of each Scenario that was
+	// actually run, for use by Report()/the structured result emitters.
+	scenarioTimings map[Scenario]scenarioTiming
+
+	// mu guards scenarios, scenarioTimings and sysdumpCount against
+	// concurrent access when parallelism > 1 runs multiple Scenarios'
+	// goroutines at once. It does not guard individual Actions: each
+	// Scenario's Run method exclusively owns the Actions it creates via
+	// NewAction, so two parallel-safe Scenarios never touch the same
+	// Action's fields directly. That invariant doesn't cover reads that
+	// span every Scenario's Actions at once, though (failedActions(),
+	// aggregatedFailureMetadata()): Run() refuses to combine
+	// WithParallelism with a per-Action sysdump policy (anything other than
+	// SysdumpPolicyNever or SysdumpPolicyAggregated) so that those
+	// cross-Scenario reads can never race with an in-flight Scenario's
+	// goroutine writing its own Action.failed.
+	mu sync.Mutex
+
+	// parallelism bounds how many ParallelSafeScenario Scenarios may run
+	// concurrently, set via WithParallelism. 0 (the default) and 1 both mean
+	// fully sequential dispatch.
+	parallelism int
+
 	// Cilium Local Redirect Policies active during this test.
 	clrps map[string]*ciliumv2.CiliumLocalRedirectPolicy
 
@@ -129,6 +173,19 @@ type Test struct {
 	// A custom sysdump policy for the given test.
 	sysdumpPolicy SysdumpPolicy
 
+	// panicPolicy controls whether runScenario recovers from a panicking
+	// Scenario.Run or lets it propagate. Defaults to PanicPolicyRecover.
+	panicPolicy PanicPolicy
+
+	// sysdumpCount tracks how many sysdumps have been collected so far,
+	// for policies that cap the total (SysdumpPolicyOnce,
+	// SysdumpPolicyOnFirstN).
+	sysdumpCount int
+
+	// sysdumpHooks are run against every sysdump.Collector created for
+	// this Test, registered via WithSysdumpHook.
+	sysdumpHooks []SysdumpHook
+
 	// List of callbacks to be executed before the test run as additional setup.
 	before []SetupFunc
 
@@ -153,6 +210,18 @@ type Test struct {
 
 	// List of functions to be called when Run() returns.
 	finalizers []func(ctx context.Context) error
+
+	// preflightChecks are fast sanity checks run before setup(), in addition
+	// to the global registry (see RegisterChecker in preflight.go). Unlike
+	// before (SetupFunc), a failing SeverityFatal Checker skips the Test
+	// with its error as the reason, rather than failing it deep inside
+	// setup.
+	preflightChecks []Checker
+
+	// reporters are notified with this Test's structured result once Run
+	// returns, regardless of whether the Test passed, failed or was
+	// skipped. See the Reporter interface in report.go.
+	reporters []Reporter
 }
 
 func (t *Test) String() string {
@@ -171,8 +240,8 @@ func (t *Test) Failed() bool {
 
 func (t *Test) FailureMessages() []string {
 	failureMessages := []string{}
-	for _, s := range t.scenarios {
-		for _, m := range s {
+	for _, s := range t.scenarioOrder {
+		for _, m := range t.scenarios[s] {
 			if m.failureMessage != "" {
 				failureMessages = append(failureMessages, m.failureMessage)
 			}
@@ -297,6 +366,14 @@ func (t *Test) willRun() (bool, string) {
 		return false, reason
 	}
 
+	// Check the Test's feature-gate expressions registered via
+	// WithFeatureExpression.
+	for _, fe := range t.featureExprs {
+		if ok, reason := fe.eval(t); !ok {
+			return false, fmt.Sprintf("unmet feature expression clause: %s", reason)
+		}
+	}
+
 	// Skip the whole Test if all of its Scenarios are excluded by the user's
 	// filter.
 	var skipped int
@@ -336,6 +413,21 @@ func (t *Test) Run(ctx context.Context, index int) error {
 		return err
 	}
 
+	// Hand this Test's structured result to any registered Reporters once
+	// it's done, including the effects of finalizers run below.
+	defer func() {
+		t.report()
+	}()
+
+	// Under SysdumpPolicyAggregated, collectSysdump() is a no-op per-Action;
+	// collect the single aggregated sysdump here instead, once all Scenarios
+	// (and finalizers) have run.
+	defer func() {
+		if t.sysdumpPolicy.mode == sysdumpModeAggregated {
+			t.collectAggregatedSysdump()
+		}
+	}()
+
 	// Steps to execute when all Scenarios have finished executing,
 	// whether they were successful or not. Scenario.Run() might call Fatal(),
 	// in which case this function executes as normal.
@@ -348,8 +440,22 @@ func (t *Test) Run(ctx context.Context, index int) error {
 		t.Failf("Test has no Scenarios [%d/%d]", index, len(t.ctx.tests))
 	}
 
+	// Per-Action sysdump collection (every mode except Never/Aggregated)
+	// reads a.failed across every Scenario's Actions from collectSysdump,
+	// called synchronously off of a failing Action. Under WithParallelism
+	// that read can race with another in-flight ParallelSafeScenario's
+	// goroutine concurrently writing its own Action's a.failed. Aggregated
+	// collection is immune, since it only runs once all parallel Scenarios
+	// have been drained by Run()'s final wg.Wait(); require it whenever
+	// parallelism is enabled instead of risking the race.
+	if t.parallelism > 1 && t.sysdumpPolicy.mode != sysdumpModeNever && t.sysdumpPolicy.mode != sysdumpModeAggregated {
+		return fmt.Errorf("test %q uses WithParallelism but its sysdump policy is neither SysdumpPolicyNever nor SysdumpPolicyAggregated: per-Action sysdump collection is not safe to run concurrently with an in-flight Scenario", t.Name())
+	}
+
 	// Skip the Test if all of its Scenarios are skipped.
 	if run, reason := t.willRun(); !run {
+		t.skipped = true
+		t.skipReason = reason
 		t.Context().skip(t, index, reason)
 		return nil
 	}
@@ -363,6 +469,13 @@ func (t *Test) Run(ctx context.Context, index int) error {
 
 	t.ctx.logger.Printf(t, "[=] [%s] Test [%s] [%d/%d]\n", t.ctx.params.TestNamespace, t.Name(), index, len(t.ctx.tests))
 
+	if reason := t.runPreflightChecks(ctx); reason != "" {
+		t.skipped = true
+		t.skipReason = reason
+		t.Context().skip(t, index, reason)
+		return nil
+	}
+
 	if err := t.setup(ctx); err != nil {
 		return fmt.Errorf("setting up test: %w", err)
 	}
@@ -377,8 +490,15 @@ func (t *Test) Run(ctx context.Context, index int) error {
 		t.ctx.Timestamp()
 	}
 
-	for s := range t.scenarios {
+	// sem bounds how many ParallelSafeScenario Scenarios' goroutines may be
+	// in flight at once; wg lets a non-parallel-safe Scenario act as a
+	// barrier by draining them first.
+	sem := make(chan struct{}, max(t.parallelism, 1))
+	var wg sync.WaitGroup
+
+	for _, s := range t.scenarioOrder {
 		if err := ctx.Err(); err != nil {
+			wg.Wait()
 			return err
 		}
 
@@ -392,11 +512,27 @@ func (t *Test) Run(ctx context.Context, index int) error {
 			continue
 		}
 
-		t.Logf("[-] Scenario [%s]", t.scenarioName(s))
+		if !t.isParallelSafe(s) {
+			// Drain in-flight parallel Scenarios before and after running a
+			// non-parallel-safe one, since it may mutate state (CNPs,
+			// Secrets, cordoned nodes, ...) that a parallel Scenario's
+			// Action could be relying on mid-flight.
+			wg.Wait()
+			t.dispatchScenario(ctx, s)
+			continue
+		}
 
-		s.Run(ctx, t)
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(s Scenario) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			t.dispatchScenario(ctx, s)
+		}(s)
 	}
 
+	wg.Wait()
+
 	if t.logBuf != nil {
 		t.ctx.logger.Printf(t, "\n")
 	}
@@ -407,6 +543,38 @@ func (t *Test) Run(ctx context.Context, index int) error {
 	return nil
 }
 
+// dispatchScenario logs and times a single Scenario's execution, recording
+// its timing under mu so it's safe to call concurrently from the worker
+// pool set up by WithParallelism.
+func (t *Test) dispatchScenario(ctx context.Context, s Scenario) {
+	t.Logf("[-] Scenario [%s]", t.scenarioName(s))
+
+	start := time.Now()
+	t.runScenario(ctx, s)
+	end := time.Now()
+
+	t.mu.Lock()
+	t.scenarioTimings[s] = scenarioTiming{start: start, end: end}
+	t.mu.Unlock()
+}
+
+// runScenario executes a single Scenario's Run method. By default
+// (PanicPolicyRecover) it recovers from any panic so that a bug in one
+// Scenario doesn't take down the whole connectivity test run, reporting a
+// recovered panic as a Test failure with a stack trace attached; opting into
+// PanicPolicyPropagate via WithPanicPolicy lets the panic propagate instead.
+func (t *Test) runScenario(ctx context.Context, s Scenario) {
+	if t.panicPolicy != PanicPolicyPropagate {
+		defer func() {
+			if r := recover(); r != nil {
+				t.Failf("Scenario [%s] panicked: %v\n%s", t.scenarioName(s), r, debug.Stack())
+			}
+		}()
+	}
+
+	s.Run(ctx, t)
+}
+
 // WithCondition takes a function containing condition check logic that
 // returns true if the test needs to be run, and false otherwise. If
 // WithCondition gets called multiple times, all the conditions need to be
@@ -527,6 +695,31 @@ type CiliumEgressGatewayPolicyParams struct {
 
 	// Includes changes for multigateway testing
 	Multigateway bool
+
+	// HonorClusterHTTPProxy, when set, resolves the cluster's configured
+	// HTTP_PROXY/HTTPS_PROXY/NO_PROXY (read from HTTPProxyConfigMap, falling
+	// back to the well-known kube-system/proxy-config ConfigMap) and installs
+	// DestinationCIDRs covering the proxy endpoint(s) plus ExcludedCIDRs for
+	// the NO_PROXY list, so the policy stays correct when clients must reach
+	// the outside world through a cluster-wide HTTP(S) proxy.
+	HonorClusterHTTPProxy bool
+
+	// HTTPProxyConfigMap overrides the ConfigMap consulted when
+	// HonorClusterHTTPProxy is set. Defaults to kube-system/proxy-config.
+	HTTPProxyConfigMap HTTPProxyConfigMapRef
+
+	// DestinationCIDRs, if set, overrides the policy's destinationCIDRs,
+	// replacing whatever the template YAML specifies. Used by scenarios
+	// that need a broader (e.g. 1.1.1.0/24) or catch-all (0.0.0.0/0)
+	// destination range together with ExcludedCIDRs.
+	DestinationCIDRs []string
+
+	// ExcludedCIDRs adds literal CIDR strings to the policy's
+	// excludedCIDRs, in addition to whatever ExcludedCIDRsConf derives
+	// automatically. Used by scenarios that need to carve specific ranges
+	// (e.g. 1.1.1.22/32, 1.1.1.240/30) out of a broader destinationCIDRs
+	// block.
+	ExcludedCIDRs []string
 }
 
 // WithCiliumEgressGatewayPolicy takes a string containing a YAML policy
@@ -578,6 +771,13 @@ func (t *Test) WithCiliumEgressGatewayPolicy(params CiliumEgressGatewayPolicyPar
 		}
 	}
 
+	if len(params.DestinationCIDRs) > 0 {
+		pl.Spec.DestinationCIDRs = nil
+		for _, cidr := range params.DestinationCIDRs {
+			pl.Spec.DestinationCIDRs = append(pl.Spec.DestinationCIDRs, ciliumv2.CIDR(cidr))
+		}
+	}
+
 	var ipv6Enabled bool
 	if status, ok := t.ctx.Feature(features.IPv6); ok && status.Enabled && versioncheck.MustCompile(">=1.18.0")(t.ctx.CiliumVersion) {
 		ipv6Enabled = true
@@ -608,6 +808,34 @@ func (t *Test) WithCiliumEgressGatewayPolicy(params CiliumEgressGatewayPolicyPar
 		}
 	}
 
+	for _, cidr := range params.ExcludedCIDRs {
+		pl.Spec.ExcludedCIDRs = append(pl.Spec.ExcludedCIDRs, ciliumv2.CIDR(cidr))
+	}
+
+	// If requested, resolve the cluster's HTTP(S) proxy configuration and
+	// route around it correctly: the proxy endpoint itself must be reachable
+	// through the egress gateway, while NO_PROXY destinations must bypass it.
+	// The ConfigMap fetch is live cluster I/O, so it's deferred into a
+	// t.before callback (like WithCertManagerCertificate's Secret polling)
+	// to run with the Test's real, cancellable context.
+	if params.HonorClusterHTTPProxy {
+		ref := params.HTTPProxyConfigMap
+		if ref == (HTTPProxyConfigMapRef{}) {
+			ref = defaultHTTPProxyConfigMapRef
+		}
+
+		t.before = append(t.before, func(ctx context.Context, t *Test, testCtx *ConnectivityTest) error {
+			proxyCIDRs, noProxyCIDRs, err := t.resolveClusterHTTPProxyCIDRs(ctx, ref, ipv6Enabled)
+			if err != nil {
+				return fmt.Errorf("resolving cluster HTTP proxy configuration: %w", err)
+			}
+
+			pl.Spec.DestinationCIDRs = append(pl.Spec.DestinationCIDRs, proxyCIDRs...)
+			pl.Spec.ExcludedCIDRs = append(pl.Spec.ExcludedCIDRs, noProxyCIDRs...)
+			return nil
+		})
+	}
+
 	t.resources = append(t.resources, &pl)
 
 	t.WithFeatureRequirements(features.RequireEnabled(features.EgressGateway))
@@ -627,11 +855,46 @@ func (t *Test) WithScenarios(sl ...Scenario) *Test {
 		}
 
 		t.scenarios[s] = make([]*Action, 0)
+		t.scenarioOrder = append(t.scenarioOrder, s)
 	}
 
 	return t
 }
 
+// ParallelSafeScenario is implemented by a Scenario whose Run method is safe
+// to execute concurrently with other parallel-safe Scenarios in the same
+// Test, for example because it only touches the Pods/Actions it creates
+// itself and performs no mutation of state shared across Scenarios (CNP
+// changes, node cordoning, Secret rotation, ...). Test.Run dispatches
+// ParallelSafeScenario Scenarios onto a worker pool bounded by
+// WithParallelism; every other Scenario continues to run strictly
+// sequentially, acting as a barrier that drains in-flight parallel
+// Scenarios before and after it runs.
+type ParallelSafeScenario interface {
+	ParallelSafe() bool
+}
+
+// isParallelSafe reports whether s opted into parallel dispatch via
+// ParallelSafeScenario, and whether the Test itself has parallelism enabled.
+func (t *Test) isParallelSafe(s Scenario) bool {
+	if t.parallelism <= 1 {
+		return false
+	}
+	ps, ok := s.(ParallelSafeScenario)
+	return ok && ps.ParallelSafe()
+}
+
+// WithParallelism bounds how many ParallelSafeScenario Scenarios may run
+// concurrently within this Test, to cut down wall-clock time on large
+// matrices (all IP families x all client/server Pods x all Scenarios).
+// Scenarios that don't implement ParallelSafeScenario, or return false from
+// it, are unaffected and keep running one at a time in registration order.
+// The default, n <= 1, preserves today's fully sequential dispatch.
+func (t *Test) WithParallelism(n int) *Test {
+	t.parallelism = n
+	return t
+}
+
 // WithFeatureRequirements adds FeatureRequirements to Test, all of which
 // must be satisfied in order for the test to be run. It adds only features
 // that are not already present in the requirements.
@@ -788,26 +1051,75 @@ func (t *Test) WithSetupFunc(f SetupFunc) *Test {
 	return t
 }
 
+// Checker, RegisterChecker, WithPreflight and runPreflightChecks live in
+// preflight.go.
+
 // WithFinalizer registers a finalizer to be executed when Run() returns.
 func (t *Test) WithFinalizer(f func(context.Context) error) *Test {
 	t.finalizers = append(t.finalizers, f)
 	return t
 }
 
-// SysdumpPolicy represents a policy for sysdump collection in case of test failure.
-type SysdumpPolicy int
+// WithReporter registers a Reporter to be handed this Test's structured
+// result once Run() returns, whether the Test passed, failed or was
+// skipped.
+func (t *Test) WithReporter(r Reporter) *Test {
+	t.reporters = append(t.reporters, r)
+	return t
+}
+
+// report hands this Test's structured result to every registered Reporter.
+// Errors are logged rather than failing the Test, since a Reporter writing
+// CI output is not part of the connectivity check itself.
+func (t *Test) report() {
+	for _, r := range t.reporters {
+		if err := r.Report(t.Report()); err != nil {
+			t.Logf("Reporter failed for Test [%s]: %s", t.Name(), err)
+		}
+	}
+}
+
+// sysdumpPolicyMode is the underlying collection strategy of a SysdumpPolicy.
+type sysdumpPolicyMode int
 
 const (
+	sysdumpModeEach sysdumpPolicyMode = iota
+	sysdumpModeOnce
+	sysdumpModeNever
+	sysdumpModeOnFirstN
+	sysdumpModeAggregated
+)
+
+// SysdumpPolicy represents a policy for sysdump collection in case of test
+// failure. Construct one of the SysdumpPolicy* values, or call
+// SysdumpPolicyOnFirstN.
+type SysdumpPolicy struct {
+	mode sysdumpPolicyMode
+	n    int
+}
+
+var (
 	// SysdumpPolicyEach enables capturing one sysdump for each failing action.
 	// This is the default and applies also when no explicit policy is specified.
-	SysdumpPolicyEach SysdumpPolicy = iota
+	SysdumpPolicyEach = SysdumpPolicy{mode: sysdumpModeEach}
 	// SysdumpPolicyOnce enables capturing only one sysdump for the given test,
 	// independently of the number of failures.
-	SysdumpPolicyOnce
+	SysdumpPolicyOnce = SysdumpPolicy{mode: sysdumpModeOnce}
 	// SysdumpPolicyNever disables sysdump collection for the given test.
-	SysdumpPolicyNever
+	SysdumpPolicyNever = SysdumpPolicy{mode: sysdumpModeNever}
+	// SysdumpPolicyAggregated defers sysdump collection until Run() returns,
+	// producing a single tarball annotated with metadata (scenario name,
+	// expected vs. observed verdict) for every failed Action in the Test,
+	// instead of one tarball per failure.
+	SysdumpPolicyAggregated = SysdumpPolicy{mode: sysdumpModeAggregated}
 )
 
+// SysdumpPolicyOnFirstN caps sysdump collection at the first n failing
+// Actions in the Test, independently of how many more fail afterwards.
+func SysdumpPolicyOnFirstN(n int) SysdumpPolicy {
+	return SysdumpPolicy{mode: sysdumpModeOnFirstN, n: n}
+}
+
 // WithSysdumpPolicy enables tuning the policy for capturing the sysdump in case
 // of test failure, which takes effect only when sysdumps have been requested by
 // the user. It is intended to be used to limit the number of sysdumps generated
@@ -818,6 +1130,46 @@ func (t *Test) WithSysdumpPolicy(policy SysdumpPolicy) *Test {
 	return t
 }
 
+// PanicPolicy controls how runScenario reacts to a Scenario.Run panic.
+type PanicPolicy int
+
+const (
+	// PanicPolicyRecover recovers from a panicking Scenario.Run, reporting
+	// it as a Test failure with a stack trace attached. This is the
+	// default: a bug in one Scenario shouldn't take down the whole
+	// connectivity test run.
+	PanicPolicyRecover PanicPolicy = iota
+	// PanicPolicyPropagate lets a Scenario.Run panic propagate instead of
+	// being recovered, for custom Scenario authors who'd rather have their
+	// test binary crash loudly (e.g. under a fuzzer or debugger) than have
+	// the panic reported as an ordinary Test failure.
+	PanicPolicyPropagate
+)
+
+// WithPanicPolicy overrides how this Test's Scenarios react to a panic in
+// Scenario.Run. The default, PanicPolicyRecover, is almost always what's
+// wanted; PanicPolicyPropagate is an opt-in for Scenario authors debugging a
+// panic who want the original stack to surface immediately.
+func (t *Test) WithPanicPolicy(policy PanicPolicy) *Test {
+	t.panicPolicy = policy
+	return t
+}
+
+// SysdumpHook lets a Test inject a targeted collector alongside (or instead
+// of relying solely on) the default full-cluster sysdump, e.g. to dump
+// specific bpf maps, run `cilium bpf egress list` on the elected gateway
+// node, or capture tcpdump on a chosen interface. It receives every Action
+// of the Test that has failed so far, including those already covered by an
+// earlier sysdump collection.
+type SysdumpHook func(ctx context.Context, t *Test, failed []*Action, collector *sysdump.Collector) error
+
+// WithSysdumpHook registers hook to run after each sysdump.Collector created
+// for one of this Test's failures has finished its own collection.
+func (t *Test) WithSysdumpHook(hook SysdumpHook) *Test {
+	t.sysdumpHooks = append(t.sysdumpHooks, hook)
+	return t
+}
+
 // NewAction creates a new Action. s must be the Scenario the Action is created
 // for, name should be a visually-distinguishable name, src is the execution
 // Pod of the action, and dst is the network target the Action will connect to.
@@ -828,8 +1180,12 @@ func (t *Test) NewAction(s Scenario, name string, src *Pod, dst TestPeer, ipFam
 	// the registered expectation function.
 	a.expEgress, a.expIngress = t.expectations(a)
 
-	// Store a list of Actions per Scenario.
+	// Store a list of Actions per Scenario. Guarded by mu since multiple
+	// ParallelSafeScenario Scenarios' goroutines may call NewAction
+	// concurrently.
+	t.mu.Lock()
 	t.scenarios[s] = append(t.scenarios[s], a)
+	t.mu.Unlock()
 
 	return a
 }
@@ -844,19 +1200,16 @@ func (t *Test) NewGenericAction(s Scenario, name string) *Action {
 
 // Scenarios returns a slice of all Scenarios belonging to the Test.
 func (t *Test) Scenarios() []Scenario {
-	var out []Scenario
-
-	for s := range t.scenarios {
-		out = append(out, s)
-	}
-
-	return out
+	return slices.Clone(t.scenarioOrder)
 }
 
 // failedActions returns a list of failed Actions in the Test.
 func (t *Test) failedActions() []*Action {
 	var out []*Action
 
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
 	for _, s := range t.scenarios {
 		for _, a := range s {
 			if a.failed {
@@ -899,7 +1252,75 @@ func (t *Test) EgressGatewayNodes() []string {
 	return out
 }
 
+// collectSysdump collects a sysdump for a single failing Action, subject to
+// t.sysdumpPolicy: SysdumpPolicyNever skips collection entirely,
+// SysdumpPolicyOnce and SysdumpPolicyOnFirstN cap the total collected,
+// and SysdumpPolicyAggregated defers collection to collectAggregatedSysdump,
+// called once from Run().
 func (t *Test) collectSysdump() {
+	t.mu.Lock()
+	switch t.sysdumpPolicy.mode {
+	case sysdumpModeNever, sysdumpModeAggregated:
+		t.mu.Unlock()
+		return
+	case sysdumpModeOnce:
+		if t.sysdumpCount > 0 {
+			t.mu.Unlock()
+			return
+		}
+	case sysdumpModeOnFirstN:
+		if t.sysdumpCount >= t.sysdumpPolicy.n {
+			t.mu.Unlock()
+			return
+		}
+	}
+	t.sysdumpCount++
+	t.mu.Unlock()
+
+	// Under WithParallelism, concurrently failing Actions across
+	// ParallelSafeScenario Scenarios can race to get here; the increment
+	// above under mu is what actually enforces the Once/OnFirstN cap, and
+	// each caller still attributes its own collection to the failed Actions
+	// observed at this point in time.
+	t.runSysdumpCollection(t.failedActions())
+}
+
+// collectAggregatedSysdump collects a single sysdump tarball for the whole
+// Test run, logging metadata (scenario name, expected vs. observed verdict)
+// for every failed Action first. It's called once from Run() when
+// SysdumpPolicyAggregated is in effect and at least one Action failed.
+func (t *Test) collectAggregatedSysdump() {
+	failed := t.failedActions()
+	if len(failed) == 0 {
+		return
+	}
+
+	t.Logf("Aggregated sysdump for %d failed action(s) in Test [%s]:\n%s", len(failed), t.Name(), t.aggregatedFailureMetadata())
+	t.runSysdumpCollection(failed)
+}
+
+// aggregatedFailureMetadata renders one line per failed Action, naming its
+// Scenario and expected egress/ingress verdicts alongside its failure
+// message, for inclusion alongside an aggregated sysdump.
+func (t *Test) aggregatedFailureMetadata() string {
+	var b strings.Builder
+
+	for s, actions := range t.scenarios {
+		for _, a := range actions {
+			if !a.failed {
+				continue
+			}
+			fmt.Fprintf(&b, "scenario=%s expEgress=%v expIngress=%v message=%q\n",
+				t.scenarioName(s), a.expEgress, a.expIngress, a.failureMessage)
+		}
+	}
+
+	return b.String()
+}
+
+// runSysdumpCollection creates a sysdump.Collector against every configured
+// client and runs it, followed by any hooks registered via WithSysdumpHook.
+func (t *Test) runSysdumpCollection(failed []*Action) {
 	for _, client := range t.ctx.Clients() {
 		collector, err := sysdump.NewCollector(client, t.ctx.params.SysdumpOptions, t.ctx.sysdumpHooks, time.Now())
 		if err != nil {
@@ -909,6 +1330,12 @@ func (t *Test) collectSysdump() {
 		if err = collector.Run(); err != nil {
 			t.Failf("Failed to collect sysdump: %v", err)
 		}
+
+		for _, hook := range t.sysdumpHooks {
+			if err := hook(context.Background(), t, failed, collector); err != nil {
+				t.Failf("Sysdump hook failed: %v", err)
+			}
+		}
 	}
 }
 
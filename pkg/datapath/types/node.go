@@ -20,6 +20,13 @@ type MTUConfiguration interface {
 	GetDeviceMTU() int
 	GetRouteMTU() int
 	GetRoutePostEncryptMTU() int
+
+	// GetMTUForPeer returns the MTU to use for traffic to peer specifically,
+	// which may be lower than GetRouteMTU() when a PMTUD subsystem has
+	// observed a smaller path MTU for that peer (see PeerMTUCache). An
+	// MTUConfiguration with no per-peer knowledge should return
+	// GetRouteMTU() unconditionally.
+	GetMTUForPeer(peer net.IP) int
 }
 
 // LocalNodeConfiguration represents the configuration of the local node
@@ -108,6 +115,16 @@ type LocalNodeConfiguration struct {
 	// subsequent calls to NodeConfigurationChanged().
 	RoutePostEncryptMTU int
 
+	// PeerMTU holds the per-peer PMTU cache consulted by the tunnel and
+	// IPsec datapath paths in place of the scalar DeviceMTU/RouteMTU, when
+	// a PMTUD subsystem has discovered a smaller path MTU for a given peer.
+	// Nil disables per-peer MTU lookups; callers should fall back to
+	// RouteMTU in that case.
+	//
+	// Mutable at runtime: entries age out and are refreshed independently
+	// of NodeConfigurationChanged().
+	PeerMTU *PeerMTUCache
+
 	// AuxiliaryPrefixes is the list of auxiliary prefixes that should be
 	// configured in addition to the node PodCIDR
 	//
@@ -207,6 +224,13 @@ func (cfg *LocalNodeConfiguration) GetIPv6PodSubnets() []*net.IPNet {
 	return cidr.CIDRsToIPNets(cfg.IPv6PodSubnets)
 }
 
+// NodeUpdate pairs the old and new definition of a node, as passed to
+// NodeHandler.NodeUpdate and batched up for NodeHandler.NodeUpdateBatch.
+type NodeUpdate struct {
+	Old nodeTypes.Node
+	New nodeTypes.Node
+}
+
 // NodeHandler handles node related events such as addition, update or deletion
 // of nodes or changes to the local node configuration.
 //
@@ -229,6 +253,32 @@ type NodeHandler interface {
 	// NodeDelete is called after a node has been deleted
 	NodeDelete(node nodeTypes.Node) error
 
+	// BatchSupported reports whether this NodeHandler implements the
+	// NodeAddBatch/NodeUpdateBatch/NodeDeleteBatch methods with genuine
+	// batching (coalescing the underlying netlink/bpf operations), rather
+	// than just looping over the per-node methods. Callers use this to
+	// decide whether batching a debounce window's worth of node events is
+	// worthwhile, or whether to dispatch them one at a time as they arrive.
+	BatchSupported() bool
+
+	// NodeAddBatch is the batched equivalent of NodeAdd, called with every
+	// node added within a debounce window. Implementations should coalesce
+	// their underlying netlink/bpf map operations (e.g. a single
+	// BPF_MAP_UPDATE_BATCH) across the whole batch rather than processing
+	// newNodes one at a time.
+	NodeAddBatch(newNodes []nodeTypes.Node) error
+
+	// NodeUpdateBatch is the batched equivalent of NodeUpdate, called with
+	// every node updated within a debounce window.
+	NodeUpdateBatch(updates []NodeUpdate) error
+
+	// NodeDeleteBatch is the batched equivalent of NodeDelete, called with
+	// every node deleted within a debounce window. Implementations should
+	// coalesce their underlying netlink/bpf map operations (e.g. a single
+	// BPF_MAP_LOOKUP_AND_DELETE_BATCH) across the whole batch rather than
+	// processing nodes one at a time.
+	NodeDeleteBatch(nodes []nodeTypes.Node) error
+
 	// AllNodeValidateImplementation is called to validate the implementation
 	// of all nodes in the node cache.
 	AllNodeValidateImplementation()
@@ -239,9 +289,64 @@ type NodeHandler interface {
 	NodeValidateImplementation(node nodeTypes.Node) error
 }
 
+// NodeHandlerBatchUnsupported is an embeddable no-op implementation of
+// NodeHandler's BatchSupported/NodeAddBatch/NodeUpdateBatch/NodeDeleteBatch
+// methods, for NodeHandler implementations that don't coalesce their
+// underlying netlink/bpf operations across a debounce window. Embedding it
+// keeps such implementers compiling against the batch methods by falling
+// back to looping over the per-node NodeAdd/NodeUpdate/NodeDelete the
+// embedding type must still provide.
+type NodeHandlerBatchUnsupported struct {
+	// Handler is the per-node NodeHandler the batch methods fall back to.
+	// It must be set to the embedding type itself (or another NodeHandler
+	// implementing the per-node methods); a nil Handler makes every batch
+	// method a no-op returning nil.
+	Handler NodeHandler
+}
+
+// BatchSupported always reports false: NodeHandlerBatchUnsupported never
+// coalesces operations, it only loops over the per-node methods.
+func (b NodeHandlerBatchUnsupported) BatchSupported() bool { return false }
+
+func (b NodeHandlerBatchUnsupported) NodeAddBatch(newNodes []nodeTypes.Node) error {
+	if b.Handler == nil {
+		return nil
+	}
+	for _, n := range newNodes {
+		if err := b.Handler.NodeAdd(n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b NodeHandlerBatchUnsupported) NodeUpdateBatch(updates []NodeUpdate) error {
+	if b.Handler == nil {
+		return nil
+	}
+	for _, u := range updates {
+		if err := b.Handler.NodeUpdate(u.Old, u.New); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b NodeHandlerBatchUnsupported) NodeDeleteBatch(nodes []nodeTypes.Node) error {
+	if b.Handler == nil {
+		return nil
+	}
+	for _, n := range nodes {
+		if err := b.Handler.NodeDelete(n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 type NodeConfigChangeHandler interface {
 	// NodeConfigurationChanged is called when the local node configuration
-	// has changed
+	// has changed.
 	NodeConfigurationChanged(config LocalNodeConfiguration) error
 }
 
@@ -255,7 +360,78 @@ type NodeIDHandler interface {
 	// DumpNodeIDs returns all node IDs and their associated IP addresses.
 	DumpNodeIDs() []*models.NodeID
 
+	// DumpNodeIDsWithProvenance returns all node IDs and their associated IP
+	// addresses, annotated with whether each entry's current in-memory
+	// value came from the BPF map or was re-allocated from the on-disk
+	// NodeIDFile during RestoreNodeIDs, so operators can debug drift
+	// between the two after a map wipe.
+	DumpNodeIDsWithProvenance() []NodeIDEntry
+
 	// RestoreNodeIDs restores node IDs and their associated IP addresses from the
-	// BPF map and into the node handler in-memory copy.
+	// BPF map and into the node handler in-memory copy. If the BPF map restore
+	// yields fewer entries than the most recent SaveNodeIDs snapshot at the
+	// handler's configured persistence path, the missing IDs are re-allocated
+	// from that file instead of being assigned fresh ones, to preserve node
+	// identity (and avoid IPsec SPI churn / connection resets) across a BPF
+	// map wipe, e.g. from a kernel upgrade with map pinning disabled or a
+	// migration between bpffs mounts. Implementations should read the file
+	// with ReadNodeIDFile and reconcile it against the live BPF map entries
+	// with MergeMissingNodeIDs.
 	RestoreNodeIDs()
+
+	// SaveNodeIDs atomically writes the current node-ID<->IP mapping to
+	// path as a NodeIDFile, for RestoreNodeIDs to fall back to if the BPF
+	// map comes back empty or incomplete. Implementations should call
+	// WriteNodeIDFile on every node-ID allocation and release; it handles
+	// the checksum and the write-to-temp-file-then-rename needed so a crash
+	// mid-write never leaves a truncated file behind.
+	SaveNodeIDs(path string) error
+}
+
+// NodeIDProvenance records where a NodeIDEntry's current value came from,
+// for DumpNodeIDsWithProvenance.
+type NodeIDProvenance string
+
+const (
+	// NodeIDProvenanceBPFMap means the entry was read back from the live
+	// BPF map during RestoreNodeIDs.
+	NodeIDProvenanceBPFMap NodeIDProvenance = "bpf-map"
+
+	// NodeIDProvenanceFile means the BPF map had no entry for this node's IP
+	// at RestoreNodeIDs time, and the value was instead re-allocated from
+	// the on-disk NodeIDFile written by a prior SaveNodeIDs.
+	NodeIDProvenanceFile NodeIDProvenance = "file"
+)
+
+// NodeIDEntry pairs a node ID <-> IP mapping with where its current value
+// was restored from, returned by DumpNodeIDsWithProvenance.
+type NodeIDEntry struct {
+	NodeID     uint16           `json:"nodeID"`
+	NodeIP     string           `json:"nodeIP"`
+	Provenance NodeIDProvenance `json:"provenance"`
+}
+
+// NodeIDFileVersion is the current version of the NodeIDFile on-disk
+// schema. RestoreNodeIDs implementations should reject files with a higher
+// version than they understand, rather than guessing at a compatible
+// subset.
+const NodeIDFileVersion = 1
+
+// NodeIDFile is the versioned, checksummed on-disk schema written by
+// SaveNodeIDs and read back by RestoreNodeIDs, so the node-ID<->IP mapping
+// survives a BPF map wipe. Checksum is computed over the JSON encoding of the
+// whole NodeIDFile (Version and Entries) with Checksum itself zeroed, so a
+// truncated or corrupted write is
+// detected rather than silently partially restored.
+type NodeIDFile struct {
+	Version  int               `json:"version"`
+	Entries  []NodeIDFileEntry `json:"entries"`
+	Checksum string            `json:"checksum"`
+}
+
+// NodeIDFileEntry is a single node-ID<->IP mapping persisted in a
+// NodeIDFile.
+type NodeIDFileEntry struct {
+	NodeID uint16 `json:"nodeID"`
+	NodeIP string `json:"nodeIP"`
 }
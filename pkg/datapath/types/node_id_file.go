@@ -0,0 +1,138 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package types
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// checksum computes NodeIDFile's checksum over the JSON encoding of the whole
+// file (Version and Entries) with Checksum itself zeroed, so a truncated or
+// corrupted write is
+// detected by Validate rather than silently partially restored.
+func (f NodeIDFile) checksum() string {
+	f.Checksum = ""
+	b, err := json.Marshal(f)
+	if err != nil {
+		// Entries is a plain []NodeIDFileEntry; marshaling it can't fail.
+		panic(fmt.Sprintf("marshaling NodeIDFile for checksum: %s", err))
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// NewNodeIDFile builds a NodeIDFile snapshotting entries at NodeIDFileVersion,
+// with Checksum populated.
+func NewNodeIDFile(entries []NodeIDFileEntry) *NodeIDFile {
+	f := &NodeIDFile{Version: NodeIDFileVersion, Entries: entries}
+	f.Checksum = f.checksum()
+	return f
+}
+
+// Validate reports an error if f has a newer Version than this build
+// understands, or if its Checksum doesn't match its Entries, which would
+// indicate a truncated or corrupted write.
+func (f *NodeIDFile) Validate() error {
+	if f.Version > NodeIDFileVersion {
+		return fmt.Errorf("node ID file version %d is newer than the version %d this build understands", f.Version, NodeIDFileVersion)
+	}
+	if want := f.checksum(); f.Checksum != want {
+		return fmt.Errorf("node ID file checksum mismatch (got %s, want %s): file may be truncated or corrupted", f.Checksum, want)
+	}
+	return nil
+}
+
+// WriteNodeIDFile atomically writes entries to path as a checksummed
+// NodeIDFile: it's marshaled into a temporary file in the same directory as
+// path and then renamed into place, so a crash or power loss mid-write never
+// leaves a truncated file for a later ReadNodeIDFile to trip over.
+func WriteNodeIDFile(path string, entries []NodeIDFileEntry) error {
+	b, err := json.Marshal(NewNodeIDFile(entries))
+	if err != nil {
+		return fmt.Errorf("marshaling node ID file: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("creating temporary node ID file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing temporary node ID file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("syncing temporary node ID file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("closing temporary node ID file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("renaming node ID file into place: %w", err)
+	}
+	return nil
+}
+
+// ReadNodeIDFile reads and validates the NodeIDFile at path. It returns
+// (nil, nil) if path doesn't exist yet, e.g. on a node's first boot before
+// any SaveNodeIDs call.
+func ReadNodeIDFile(path string) (*NodeIDFile, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading node ID file: %w", err)
+	}
+
+	var f NodeIDFile
+	if err := json.Unmarshal(b, &f); err != nil {
+		return nil, fmt.Errorf("parsing node ID file: %w", err)
+	}
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// MergeMissingNodeIDs fills in the node-ID<->IP mappings present in file but
+// absent from bpfMapEntries (matched by NodeIP), as RestoreNodeIDs falls
+// back to doing when the BPF map comes back empty or incomplete after a
+// wipe. Entries already present in bpfMapEntries are returned unchanged and
+// take precedence; entries pulled in from file are tagged
+// NodeIDProvenanceFile so DumpNodeIDsWithProvenance can surface the drift. A
+// nil file is a no-op: bpfMapEntries is returned as-is.
+func MergeMissingNodeIDs(bpfMapEntries []NodeIDEntry, file *NodeIDFile) []NodeIDEntry {
+	if file == nil {
+		return bpfMapEntries
+	}
+
+	merged := make([]NodeIDEntry, len(bpfMapEntries), len(bpfMapEntries)+len(file.Entries))
+	copy(merged, bpfMapEntries)
+
+	haveIP := make(map[string]struct{}, len(bpfMapEntries))
+	for _, e := range bpfMapEntries {
+		haveIP[e.NodeIP] = struct{}{}
+	}
+
+	for _, e := range file.Entries {
+		if _, ok := haveIP[e.NodeIP]; ok {
+			continue
+		}
+		merged = append(merged, NodeIDEntry{
+			NodeID:     e.NodeID,
+			NodeIP:     e.NodeIP,
+			Provenance: NodeIDProvenanceFile,
+		})
+	}
+	return merged
+}
@@ -0,0 +1,164 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package types
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultPeerMTUTTL is how long an observed per-peer PMTU is trusted
+	// before GetMTUForPeer falls back to the node-wide MTUConfiguration,
+	// per the soft-state refresh semantics of RFC 1191/RFC 8201.
+	DefaultPeerMTUTTL = 10 * time.Minute
+
+	// MinPeerMTUv4 is the default floor GetMTUForPeer/PeerMTUUpdated apply
+	// to an IPv4 peer's MTU, the minimum reassembly buffer size guaranteed
+	// by RFC 791. PeerMTUConfig.MinMTUv4 overrides it, lower or higher.
+	MinPeerMTUv4 = 576
+
+	// MinPeerMTUv6 is the default floor GetMTUForPeer/PeerMTUUpdated apply
+	// to an IPv6 peer's MTU, the IPv6 minimum link MTU from RFC 8200.
+	// PeerMTUConfig.MinMTUv6 overrides it, lower or higher.
+	MinPeerMTUv6 = 1280
+)
+
+// PeerMTUWriter is implemented by a PMTU discovery subsystem (e.g. one
+// listening for ICMP "fragmentation needed"/"packet too big" on the tunnel
+// device) to feed observed path MTUs into a PeerMTUCache.
+type PeerMTUWriter interface {
+	// PeerMTUUpdated records mtu as the most recently observed path MTU to
+	// peer, superseding any previous value and resetting its TTL. mtu is
+	// clamped to the configured floor before being stored, so a malicious
+	// or misbehaving peer cannot drive the effective MTU used for it below
+	// MinPeerMTUv4/MinPeerMTUv6.
+	PeerMTUUpdated(peer net.IP, mtu int)
+}
+
+// PeerMTUConfig configures a PeerMTUCache.
+type PeerMTUConfig struct {
+	// Enabled is the kill-switch for per-peer PMTU tracking. When false,
+	// PeerMTUUpdated is a no-op and GetMTUForPeer always falls back to the
+	// node-wide MTUConfiguration, as if no per-peer subsystem were running.
+	Enabled bool
+
+	// TTL bounds how long an observed peer MTU is trusted before
+	// GetMTUForPeer reverts to the node-wide fallback. Zero means
+	// DefaultPeerMTUTTL.
+	TTL time.Duration
+
+	// MinMTUv4 and MinMTUv6 floor the MTU accepted from PeerMTUUpdated and
+	// returned by GetMTUForPeer, so a peer cannot drive the effective MTU
+	// used for it low enough to blackhole traffic. Zero means
+	// MinPeerMTUv4/MinPeerMTUv6.
+	MinMTUv4 int
+	MinMTUv6 int
+}
+
+func (c PeerMTUConfig) ttl() time.Duration {
+	if c.TTL > 0 {
+		return c.TTL
+	}
+	return DefaultPeerMTUTTL
+}
+
+func (c PeerMTUConfig) floor(peer net.IP) int {
+	if peer.To4() != nil {
+		if c.MinMTUv4 > 0 {
+			return c.MinMTUv4
+		}
+		return MinPeerMTUv4
+	}
+	if c.MinMTUv6 > 0 {
+		return c.MinMTUv6
+	}
+	return MinPeerMTUv6
+}
+
+type peerMTUEntry struct {
+	mtu     int
+	expires time.Time
+}
+
+// PeerMTUCache is a TTL'd table of per-peer path MTUs observed by a PMTUD
+// subsystem, consulted by the tunnel and IPsec datapath paths in place of
+// the scalar DeviceMTU/RouteMTU when a fresher, more specific value is
+// available for a given peer. It implements both MTUConfiguration (falling
+// back to a wrapped MTUConfiguration for peers with no cached entry, or
+// when Config.Enabled is false) and PeerMTUWriter.
+type PeerMTUCache struct {
+	fallback MTUConfiguration
+	cfg      PeerMTUConfig
+
+	mu      sync.RWMutex
+	entries map[string]peerMTUEntry
+}
+
+// NewPeerMTUCache returns a PeerMTUCache that falls back to fallback's
+// node-wide MTUs for any peer without a live cached entry.
+func NewPeerMTUCache(fallback MTUConfiguration, cfg PeerMTUConfig) *PeerMTUCache {
+	return &PeerMTUCache{
+		fallback: fallback,
+		cfg:      cfg,
+		entries:  make(map[string]peerMTUEntry),
+	}
+}
+
+// GetDeviceMTU implements MTUConfiguration by delegating to the fallback,
+// node-wide configuration; there is no meaningful per-peer device MTU.
+func (c *PeerMTUCache) GetDeviceMTU() int {
+	return c.fallback.GetDeviceMTU()
+}
+
+// GetRouteMTU implements MTUConfiguration by delegating to the fallback,
+// node-wide configuration. Use GetMTUForPeer for the per-peer value.
+func (c *PeerMTUCache) GetRouteMTU() int {
+	return c.fallback.GetRouteMTU()
+}
+
+// GetRoutePostEncryptMTU implements MTUConfiguration by delegating to the
+// fallback, node-wide configuration.
+func (c *PeerMTUCache) GetRoutePostEncryptMTU() int {
+	return c.fallback.GetRoutePostEncryptMTU()
+}
+
+// GetMTUForPeer returns the most recently observed, non-expired path MTU to
+// peer, or the node-wide GetRouteMTU() if no live entry exists or PMTU
+// tracking is disabled via PeerMTUConfig.Enabled.
+func (c *PeerMTUCache) GetMTUForPeer(peer net.IP) int {
+	if !c.cfg.Enabled {
+		return c.fallback.GetRouteMTU()
+	}
+
+	c.mu.RLock()
+	entry, ok := c.entries[peer.String()]
+	c.mu.RUnlock()
+
+	if !ok || time.Now().After(entry.expires) {
+		return c.fallback.GetRouteMTU()
+	}
+	return entry.mtu
+}
+
+// PeerMTUUpdated implements PeerMTUWriter, clamping mtu to the configured
+// floor before storing it with a fresh TTL. A no-op when PMTU tracking is
+// disabled via PeerMTUConfig.Enabled.
+func (c *PeerMTUCache) PeerMTUUpdated(peer net.IP, mtu int) {
+	if !c.cfg.Enabled {
+		return
+	}
+
+	if floor := c.cfg.floor(peer); mtu < floor {
+		mtu = floor
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[peer.String()] = peerMTUEntry{
+		mtu:     mtu,
+		expires: time.Now().Add(c.cfg.ttl()),
+	}
+}
@@ -0,0 +1,145 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package types
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+)
+
+// TunnelMTUReprogrammer is implemented by the linux node handler to push an
+// MTU that PMTUObserver just recorded in a PeerMTUWriter down into the
+// datapath: the tunnel device's per-peer route MTU and the TCP MSS clamp
+// entries in the NAT/encryption bpf maps. Reprogramming those is inherently
+// netlink/bpf-map work and so lives outside this package; PMTUObserver only
+// owns discovering the MTU and keeping PeerMTUCache current.
+type TunnelMTUReprogrammer interface {
+	// ReprogramPeerMTU updates the tunnel route MTU and MSS clamp bpf map
+	// entries for peer to mtu. Called with the same mtu that was just
+	// handed to PeerMTUWriter.PeerMTUUpdated, floored the way PeerMTUCache
+	// floors it when the writer is a *PeerMTUCache, so the two stay in sync.
+	ReprogramPeerMTU(peer net.IP, mtu int) error
+}
+
+// PMTUObserverConfig configures a PMTUObserver.
+type PMTUObserverConfig struct {
+	// Device is the tunnel device ICMP PMTU messages are expected to
+	// arrive on (e.g. "cilium_vxlan"), recorded here for logging/errors;
+	// the observer itself listens on a raw ICMP socket rather than binding
+	// to the device directly.
+	Device string
+}
+
+// PMTUObserver listens on a raw ip4:icmp socket on all interfaces for ICMPv4
+// "fragmentation needed" (RFC 1191) messages; ICMPv6 "packet too big" (RFC
+// 8201) messages are not handled, so IPv6 peers get no cache entries. It
+// feeds the path MTUs they report into a PeerMTUWriter (normally a
+// *PeerMTUCache), optionally asking a TunnelMTUReprogrammer to push the new
+// MTU into the datapath. This is PeerMTUCache's producer: without it the
+// cache never has an entry for GetMTUForPeer to find, and every lookup falls
+// back to the node-wide MTU.
+type PMTUObserver struct {
+	cfg    PMTUObserverConfig
+	writer PeerMTUWriter
+	// reprog is optional: a nil reprog still keeps the cache current for
+	// GetMTUForPeer, it just skips pushing the new MTU into the bpf maps
+	// (e.g. when running without encapsulation, where there's no tunnel
+	// route/MSS clamp entry to update).
+	reprog TunnelMTUReprogrammer
+}
+
+// NewPMTUObserver returns a PMTUObserver recording the path MTUs it observes
+// into writer, and, if reprog is non-nil, additionally reprogramming the
+// datapath's per-peer tunnel/MSS-clamp state to match.
+func NewPMTUObserver(cfg PMTUObserverConfig, writer PeerMTUWriter, reprog TunnelMTUReprogrammer) *PMTUObserver {
+	return &PMTUObserver{cfg: cfg, writer: writer, reprog: reprog}
+}
+
+// Run listens for ICMP PMTU messages until ctx is canceled, blocking the
+// calling goroutine. Callers should run it in its own goroutine for the
+// lifetime of the agent.
+func (o *PMTUObserver) Run(ctx context.Context) error {
+	conn, err := icmp.ListenPacket("ip4:icmp", "0.0.0.0")
+	if err != nil {
+		return fmt.Errorf("listening for ICMP PMTU messages on %s: %w", o.cfg.Device, err)
+	}
+	defer conn.Close()
+
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	buf := make([]byte, 1500)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("reading ICMP PMTU message: %w", err)
+		}
+
+		mtu, ok := parseFragNeededMTU(buf[:n])
+		if !ok {
+			continue
+		}
+		// The path MTU applies to the offending datagram's destination, quoted
+		// in the message after the 8-byte ICMP header, not to the router that
+		// sent the ICMP message.
+		if n < 8+ipv4.HeaderLen {
+			continue
+		}
+		peer := net.IPv4(buf[24], buf[25], buf[26], buf[27])
+		if c, ok := o.writer.(*PeerMTUCache); ok {
+			if floor := c.cfg.floor(peer); mtu < floor {
+				mtu = floor
+			}
+		}
+
+		o.writer.PeerMTUUpdated(peer, mtu)
+		if o.reprog != nil {
+			if err := o.reprog.ReprogramPeerMTU(peer, mtu); err != nil {
+				return fmt.Errorf("reprogramming tunnel MTU for peer %s: %w", peer, err)
+			}
+		}
+	}
+}
+
+// parseFragNeededMTU extracts the next-hop MTU from an ICMPv4 "fragmentation
+// needed" (type 3, code 4) message, per RFC 1191: the 2 bytes conventionally
+// unused in a Destination Unreachable message are repurposed to carry the
+// next-hop MTU, immediately before the offending datagram's own IP header.
+func parseFragNeededMTU(raw []byte) (int, bool) {
+	msg, err := icmp.ParseMessage(1 /* ianaProtocolICMP */, raw)
+	if err != nil || msg.Type != ipv4.ICMPTypeDestinationUnreachable {
+		return 0, false
+	}
+	if msg.Code != 4 /* fragmentation needed and DF set */ {
+		return 0, false
+	}
+	// raw[4:8] holds the unused byte, the RFC 4884 length byte and the
+	// next-hop MTU; DstUnreach.Data only starts after them, at the quoted
+	// datagram, so the MTU is read from raw itself.
+	if _, ok := msg.Body.(*icmp.DstUnreach); !ok || len(raw) < 8 {
+		return 0, false
+	}
+	mtu := int(raw[6])<<8 | int(raw[7])
+	return mtu, mtu > 0
+}
+
+func addrToIP(addr net.Addr) net.IP {
+	switch a := addr.(type) {
+	case *net.IPAddr:
+		return a.IP
+	case *net.UDPAddr:
+		return a.IP
+	default:
+		return nil
+	}
+}
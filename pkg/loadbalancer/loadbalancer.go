@@ -0,0 +1,672 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package loadbalancer
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+
+	cmtypes "github.com/cilium/cilium/pkg/clustermesh/types"
+)
+
+// L4Type is the type used to represent the L4 protocol of an address, or the
+// fact that the address does not carry any L4 protocol information at all
+// (NONE).
+type L4Type string
+
+const (
+	// NONE is the protocol to use when no protocol is provided. It is the
+	// same as specifying TCP but is used to represent a protocol-less
+	// address.
+	NONE L4Type = "NONE"
+
+	// TCP is the constant used to refer to the TCP protocol
+	TCP L4Type = "TCP"
+
+	// UDP is the constant used to refer to the UDP protocol
+	UDP L4Type = "UDP"
+
+	// SCTP is the constant used to refer to the SCTP protocol
+	SCTP L4Type = "SCTP"
+
+	// UNIX is the constant used to refer to a stream Unix Domain Socket.
+	// L3n4Addr values carrying this protocol are not IP:port endpoints,
+	// they carry a filesystem path instead.
+	UNIX L4Type = "UNIX"
+
+	// UNIXGRAM is the constant used to refer to a datagram Unix Domain
+	// Socket, including the abstract-namespace form (`unix-abstract://`).
+	UNIXGRAM L4Type = "UNIXGRAM"
+)
+
+// isUnixSocket returns true for the L4Types that are backed by a
+// filesystem (or abstract-namespace) path rather than an AddrCluster:port.
+func (l L4Type) isUnixSocket() bool {
+	return l == UNIX || l == UNIXGRAM
+}
+
+// l4TypeByte is the single-byte tag written into Bytes() to distinguish
+// protocols without growing the encoding for the common IP case.
+func l4TypeByte(l L4Type) byte {
+	switch l {
+	case TCP:
+		return 'T'
+	case UDP:
+		return 'U'
+	case SCTP:
+		return 'S'
+	case UNIX:
+		return 'X'
+	case UNIXGRAM:
+		return 'G'
+	default:
+		return '?'
+	}
+}
+
+// PortSpan is an inclusive range of ports, [From, To]. A single port is
+// represented with From == To.
+type PortSpan struct {
+	From uint16
+	To   uint16
+}
+
+// Contains returns true if port falls within the span.
+func (p PortSpan) Contains(port uint16) bool {
+	return port >= p.From && port <= p.To
+}
+
+// overlaps returns true if the two spans share at least one port.
+func (p PortSpan) overlaps(o PortSpan) bool {
+	return p.From <= o.To && o.From <= p.To
+}
+
+// L4Ports is a set of ports described as a sorted, non-overlapping list of
+// PortSpans, used by L4Addr to describe a port range (`8000-8100`) or a list
+// of discrete ports (`22,3389`) rather than a single port.
+type L4Ports []PortSpan
+
+// NewL4Ports builds a L4Ports out of the given spans, sorting and merging
+// adjacent/overlapping spans so that Bytes() and String() are stable.
+func NewL4Ports(spans ...PortSpan) L4Ports {
+	if len(spans) == 0 {
+		return nil
+	}
+
+	merged := slices.Clone(spans)
+	slices.SortFunc(merged, func(a, b PortSpan) int {
+		switch {
+		case a.From < b.From:
+			return -1
+		case a.From > b.From:
+			return 1
+		default:
+			return 0
+		}
+	})
+
+	out := merged[:1]
+	for _, s := range merged[1:] {
+		last := &out[len(out)-1]
+		if int(s.From) <= int(last.To)+1 {
+			if s.To > last.To {
+				last.To = s.To
+			}
+			continue
+		}
+		out = append(out, s)
+	}
+
+	return L4Ports(out)
+}
+
+// ParsePorts parses the port portion of a L3n4Addr string, accepting a
+// single port ("8080"), a range ("8000-8100"), or a comma-separated list of
+// ports and/or ranges ("22,3389", "22,8000-8100"). A plain single port is
+// returned via the port return value, with ports == nil, so that the common
+// case keeps using the existing L4Addr.Port field.
+func ParsePorts(s string) (port uint16, ports L4Ports, err error) {
+	if !strings.ContainsAny(s, ",-") {
+		v, err := strconv.ParseUint(s, 10, 16)
+		if err != nil {
+			return 0, nil, fmt.Errorf("invalid port %q: %w", s, err)
+		}
+		return uint16(v), nil, nil
+	}
+
+	var spans []PortSpan
+	for _, part := range strings.Split(s, ",") {
+		lo, hi, isRange := strings.Cut(part, "-")
+		from, err := strconv.ParseUint(lo, 10, 16)
+		if err != nil {
+			return 0, nil, fmt.Errorf("invalid port %q: %w", part, err)
+		}
+		to := from
+		if isRange {
+			to, err = strconv.ParseUint(hi, 10, 16)
+			if err != nil {
+				return 0, nil, fmt.Errorf("invalid port %q: %w", part, err)
+			}
+			if to < from {
+				return 0, nil, fmt.Errorf("invalid port range %q: end before start", part)
+			}
+		}
+		spans = append(spans, PortSpan{From: uint16(from), To: uint16(to)})
+	}
+
+	merged := NewL4Ports(spans...)
+	if len(merged) == 1 && merged[0].From == merged[0].To {
+		return merged[0].From, nil, nil
+	}
+	return 0, merged, nil
+}
+
+// Contains returns true if port is covered by any of the L4Ports' spans.
+func (p L4Ports) Contains(port uint16) bool {
+	for _, s := range p {
+		if s.Contains(port) {
+			return true
+		}
+	}
+	return false
+}
+
+// Overlaps returns true if p and o share at least one port.
+func (p L4Ports) Overlaps(o L4Ports) bool {
+	for _, a := range p {
+		for _, b := range o {
+			if a.overlaps(b) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Bytes returns a compact, stable encoding of the port set: a one-byte span
+// count followed by From/To pairs (2 bytes each, big-endian), in sorted,
+// merged order so that equal port sets always hash the same.
+func (p L4Ports) Bytes() []byte {
+	b := make([]byte, 0, 1+4*len(p))
+	b = append(b, byte(len(p)))
+	for _, s := range p {
+		var buf [4]byte
+		binary.BigEndian.PutUint16(buf[0:2], s.From)
+		binary.BigEndian.PutUint16(buf[2:4], s.To)
+		b = append(b, buf[:]...)
+	}
+	return b
+}
+
+// String returns the canonical textual form of the port set, usable inside
+// L3n4Addr.ParseFromString (e.g. "22,3389" or "8000-8100").
+func (p L4Ports) String() string {
+	parts := make([]string, 0, len(p))
+	for _, s := range p {
+		if s.From == s.To {
+			parts = append(parts, strconv.FormatUint(uint64(s.From), 10))
+		} else {
+			parts = append(parts, fmt.Sprintf("%d-%d", s.From, s.To))
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// L4Addr is an abstraction for the layer 4 address. Note that the `NONE`
+// protocol is used to represent a port with any protocol.
+//
+// Either Port or Ports is set: Ports is only populated when the address
+// describes more than one port (a range or a list), in which case Port is
+// left at its zero value.
+//
+// +k8s:deepcopy-gen=true
+// +deepequal-gen=true
+type L4Addr struct {
+	Protocol L4Type
+	Port     uint16
+	Ports    L4Ports
+}
+
+// NewL4Addr creates a new L4Addr.
+func NewL4Addr(protocol L4Type, number uint16) *L4Addr {
+	return &L4Addr{Protocol: protocol, Port: number}
+}
+
+// DeepEqual returns true if both L4Addr are equal.
+func (l *L4Addr) DeepEqual(o *L4Addr) bool {
+	if l == nil {
+		return o == nil
+	}
+	if o == nil {
+		return false
+	}
+	return l.Protocol == o.Protocol && l.Port == o.Port && slices.Equal(l.Ports, o.Ports)
+}
+
+// Scope for the a L3n4Addr
+const (
+	// ScopeExternal is the default scope of a service, which is reachable
+	// from outside the node the backend runs on.
+	ScopeExternal = iota
+	// ScopeInternal denotes a service that is only reachable from within
+	// the node the backend runs on (e.g. the internalTrafficPolicy=Local
+	// scope of a NodePort/ExternalIPs service).
+	ScopeInternal
+)
+
+// L3n4Addr is used to store, as an unique L3+L4 address in the KVStore. It
+// also can represent a L3+L4 address backed by a Unix Domain Socket path
+// instead of an AddrCluster, when Protocol is UNIX or UNIXGRAM; in that case
+// AddrCluster is the zero value and Path holds the socket path.
+//
+// +k8s:deepcopy-gen=true
+// +deepequal-gen=true
+type L3n4Addr struct {
+	L4Addr
+	AddrCluster cmtypes.AddrCluster
+	Scope       uint8
+
+	// Path is the Unix Domain Socket path this address refers to. It is
+	// only set (and only meaningful) when Protocol is UNIX or UNIXGRAM,
+	// in which case AddrCluster is left at its zero value. Socket-backed
+	// addresses are not routable: callers building a SvcFlagParam for
+	// such a service should carry Path over into SvcFlagParam.Path so
+	// NewSvcFlag forces the service non-routable regardless of
+	// IsRoutable.
+	Path string
+}
+
+// NewL3n4Addr creates a new L3n4Addr.
+func NewL3n4Addr(protocol L4Type, addrCluster cmtypes.AddrCluster, portNumber uint16, scope uint8) *L3n4Addr {
+	lbport := NewL4Addr(protocol, portNumber)
+
+	addr := L3n4Addr{L4Addr: *lbport, AddrCluster: addrCluster, Scope: scope}
+
+	return &addr
+}
+
+// NewUnixL3n4Addr creates a new L3n4Addr representing a Unix Domain Socket
+// path. protocol must be UNIX or UNIXGRAM.
+func NewUnixL3n4Addr(protocol L4Type, path string, scope uint8) (*L3n4Addr, error) {
+	if !protocol.isUnixSocket() {
+		return nil, fmt.Errorf("protocol %q is not a Unix Domain Socket protocol", protocol)
+	}
+	if path == "" {
+		return nil, fmt.Errorf("empty Unix Domain Socket path")
+	}
+	return &L3n4Addr{L4Addr: L4Addr{Protocol: protocol}, Scope: scope, Path: path}, nil
+}
+
+// IsUnixSocket returns true if the address refers to a Unix Domain Socket
+// path rather than an AddrCluster:port endpoint.
+func (a *L3n4Addr) IsUnixSocket() bool {
+	return a.Protocol.isUnixSocket()
+}
+
+// DeepEqual returns true if both L3n4Addr are equal.
+func (a *L3n4Addr) DeepEqual(o *L3n4Addr) bool {
+	if a == nil {
+		return o == nil
+	}
+	if o == nil {
+		return false
+	}
+	if !a.L4Addr.DeepEqual(&o.L4Addr) {
+		return false
+	}
+	if a.Scope != o.Scope || a.Path != o.Path {
+		return false
+	}
+	return a.AddrCluster == o.AddrCluster
+}
+
+// String returns the string representation of the L3n4Addr, suitable for
+// round-tripping through ParseFromString.
+func (a *L3n4Addr) String() string {
+	return a.format(true)
+}
+
+// StringWithProtocol returns the L3n4Addr string with the protocol always
+// included, even for NONE.
+func (a *L3n4Addr) StringWithProtocol() string {
+	return a.format(true)
+}
+
+func (a *L3n4Addr) format(withProtocol bool) string {
+	if a.IsUnixSocket() {
+		var scheme string
+		switch a.Protocol {
+		case UNIX:
+			scheme = "unix"
+		case UNIXGRAM:
+			scheme = "unix-abstract"
+		}
+		return fmt.Sprintf("%s://%s", scheme, a.Path)
+	}
+
+	ip := a.AddrCluster.Addr()
+	host := ip.String()
+	if cluster := a.AddrCluster.ClusterID(); cluster != 0 {
+		host = fmt.Sprintf("%s@%d", host, cluster)
+	}
+	if ip.Is6() {
+		host = "[" + host + "]"
+	}
+
+	var portStr string
+	if len(a.Ports) > 0 {
+		portStr = a.Ports.String()
+	} else {
+		portStr = strconv.FormatUint(uint64(a.Port), 10)
+	}
+
+	s := fmt.Sprintf("%s:%s", host, portStr)
+	if withProtocol {
+		s = fmt.Sprintf("%s/%s", s, a.Protocol)
+	}
+	if a.Scope == ScopeInternal {
+		s += "/i"
+	}
+	return s
+}
+
+// ParseFromString parses s and populates a. It accepts any of the forms
+// handled by l3n4AddrParsers: the canonical `ip:port/PROTO[/scope]` legacy
+// form, `unix://path` / `unix-abstract://path` for Unix Domain Socket
+// addresses, and the `proto://ip:port?scope=...&cluster=...` URI form. See
+// parse.go for the registry and individual parsers.
+func (a *L3n4Addr) ParseFromString(s string) error {
+	var errs error
+	for _, p := range l3n4AddrParsers {
+		matched, err := p(s, a)
+		if matched {
+			return err
+		}
+		if err != nil {
+			errs = errors.Join(errs, err)
+		}
+	}
+	if errs != nil {
+		return errs
+	}
+	return fmt.Errorf("unrecognized L3n4Addr %q", s)
+}
+
+// MarshalYAML marshals the L3n4Addr to its compact legacy string form.
+func (a L3n4Addr) MarshalYAML() (any, error) {
+	return a.String(), nil
+}
+
+// UnmarshalYAML unmarshals the L3n4Addr from its compact legacy string form.
+func (a *L3n4Addr) UnmarshalYAML(unmarshal func(any) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	return a.ParseFromString(s)
+}
+
+var _ yaml.Marshaler = L3n4Addr{}
+var _ yaml.Unmarshaler = &L3n4Addr{}
+
+// Bytes returns a stable, collision-free byte encoding of the address,
+// suitable for use as a hash key. IP-backed addresses are encoded as
+// IP(16) || ClusterID(4) || Port(2) || L4Type(1) || Scope(1), with the
+// encoded Ports appended when a port range/list is in use (Port is then 0
+// and carries no information on its own). Unix Domain Socket addresses use a
+// distinct tag byte so they can never collide with an IP encoding, followed
+// by a length-prefixed path.
+func (a *L3n4Addr) Bytes() []byte {
+	if a.IsUnixSocket() {
+		b := make([]byte, 0, 1+4+len(a.Path)+1)
+		b = append(b, 0xff) // tag: not a valid IPv4-mapped prefix byte
+		var lenBuf [4]byte
+		binary.BigEndian.PutUint32(lenBuf[:], uint32(len(a.Path)))
+		b = append(b, lenBuf[:]...)
+		b = append(b, a.Path...)
+		b = append(b, l4TypeByte(a.Protocol))
+		return b
+	}
+
+	b := make([]byte, 0, 16+4+2+1+1)
+	ip16 := a.AddrCluster.As16()
+	b = append(b, ip16[:]...)
+
+	var clusterBuf [4]byte
+	binary.BigEndian.PutUint32(clusterBuf[:], a.AddrCluster.ClusterID())
+	b = append(b, clusterBuf[:]...)
+
+	var portBuf [2]byte
+	binary.BigEndian.PutUint16(portBuf[:], a.Port)
+	b = append(b, portBuf[:]...)
+
+	b = append(b, l4TypeByte(a.Protocol))
+	b = append(b, a.Scope)
+
+	// Only append the port-set encoding when it's actually in use, so the
+	// single-port encoding above (the overwhelmingly common case) doesn't
+	// grow by a byte.
+	if len(a.Ports) > 0 {
+		b = append(b, a.Ports.Bytes()...)
+	}
+
+	return b
+}
+
+// Hash returns a hash of the L3n4Addr suitable for use as a map key.
+func (a *L3n4Addr) Hash() string {
+	sum := sha256.Sum256(a.Bytes())
+	return string(sum[:])
+}
+
+// L3n4AddrID is used to store, as an unique L3+L4 address in the KVStore, an
+// ID provided by the kvstore.
+//
+// +k8s:deepcopy-gen=true
+// +deepequal-gen=true
+type L3n4AddrID struct {
+	L3n4Addr
+	ID ID
+}
+
+// ID is the type used for the unique service identifier.
+type ID uint32
+
+// DeepEqual returns true if both L3n4AddrID are equal.
+func (a *L3n4AddrID) DeepEqual(o *L3n4AddrID) bool {
+	if a == nil {
+		return o == nil
+	}
+	if o == nil {
+		return false
+	}
+	return a.ID == o.ID && a.L3n4Addr.DeepEqual(&o.L3n4Addr)
+}
+
+// SVCType is a type of a service.
+type SVCType string
+
+const (
+	SVCTypeNone          SVCType = ""
+	SVCTypeHostPort      SVCType = "HostPort"
+	SVCTypeClusterIP     SVCType = "ClusterIP"
+	SVCTypeNodePort      SVCType = "NodePort"
+	SVCTypeExternalIPs   SVCType = "ExternalIPs"
+	SVCTypeLoadBalancer  SVCType = "LoadBalancer"
+	SVCTypeLocalRedirect SVCType = "LocalRedirect"
+)
+
+// ServiceFlags is the datapath representation of the service attributes
+// that don't fit into the L3n4Addr/backend model (routability, traffic
+// policy scopes, etc).
+//
+// +k8s:deepcopy-gen=true
+type ServiceFlags uint16
+
+const (
+	serviceFlagNone ServiceFlags = 0
+
+	serviceFlagExternalIPs ServiceFlags = 1 << iota
+	serviceFlagNodePort
+	serviceFlagLocalRedirect
+	serviceFlagLoadBalancer
+	serviceFlagL7LoadBalancer
+	serviceFlagExtLocalScope
+	serviceFlagIntLocalScope
+	serviceFlagTwoScopes
+	serviceFlagRoutable
+)
+
+// SvcFlagParam contains the information required to compute the
+// ServiceFlags of a service via NewSvcFlag.
+type SvcFlagParam struct {
+	SvcType         SVCType
+	SvcExtLocal     bool
+	SvcIntLocal     bool
+	SessionAffinity bool
+	IsRoutable      bool
+	L7LoadBalancer  bool
+
+	// Path is the Unix Domain Socket path of the service's frontend, if
+	// any (see L3n4Addr.Path). When set, NewSvcFlag always treats the
+	// service as non-routable, regardless of IsRoutable: a UDS frontend
+	// has no AddrCluster/port a remote node could route to.
+	Path string
+}
+
+// NewSvcFlag creates service flag from a SvcFlagParam.
+func NewSvcFlag(p *SvcFlagParam) ServiceFlags {
+	var flags ServiceFlags
+
+	switch p.SvcType {
+	case SVCTypeExternalIPs:
+		flags |= serviceFlagExternalIPs
+	case SVCTypeNodePort:
+		flags |= serviceFlagNodePort
+	case SVCTypeLocalRedirect:
+		flags |= serviceFlagLocalRedirect
+	case SVCTypeLoadBalancer:
+		flags |= serviceFlagLoadBalancer
+	case SVCTypeClusterIP:
+		flags |= serviceFlagNone
+	}
+
+	if p.SvcExtLocal {
+		flags |= serviceFlagExtLocalScope
+	}
+	if p.SvcIntLocal {
+		flags |= serviceFlagIntLocalScope
+	}
+	// A service has "two scopes" when its external and internal traffic
+	// policies disagree on locality, which only matters for service
+	// types that expose both an external and an internal frontend.
+	if p.SvcExtLocal != p.SvcIntLocal && (p.SvcType == SVCTypeNodePort || p.SvcType == SVCTypeExternalIPs) {
+		flags |= serviceFlagTwoScopes
+	}
+
+	if p.IsRoutable && p.Path == "" {
+		flags |= serviceFlagRoutable
+	}
+
+	if p.L7LoadBalancer {
+		flags |= serviceFlagL7LoadBalancer
+	}
+
+	return flags
+}
+
+// String returns the string representation of ServiceFlags, intended for
+// logging and CLI output.
+func (s ServiceFlags) String() string {
+	var parts []string
+
+	switch {
+	case s&serviceFlagLoadBalancer != 0:
+		parts = append(parts, "LoadBalancer")
+	case s&serviceFlagNodePort != 0:
+		parts = append(parts, "NodePort")
+	case s&serviceFlagExternalIPs != 0:
+		parts = append(parts, "ExternalIPs")
+	case s&serviceFlagLocalRedirect != 0:
+		parts = append(parts, "LocalRedirect")
+	default:
+		parts = append(parts, "ClusterIP")
+	}
+
+	if s&serviceFlagExtLocalScope != 0 {
+		parts = append(parts, "Local")
+	}
+	if s&serviceFlagIntLocalScope != 0 {
+		parts = append(parts, "InternalLocal")
+	}
+	if s&serviceFlagRoutable == 0 {
+		parts = append(parts, "non-routable")
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// ServiceName represents the name of a service, optionally scoped to a
+// remote cluster in a clustermesh.
+//
+// +k8s:deepcopy-gen=true
+// +deepequal-gen=true
+type ServiceName struct {
+	Name      string
+	Namespace string
+	Cluster   string
+}
+
+// String returns the "[cluster/]namespace/name" representation of the
+// service name.
+func (n ServiceName) String() string {
+	if n.Cluster != "" {
+		return n.Cluster + "/" + n.Namespace + "/" + n.Name
+	}
+	return n.Namespace + "/" + n.Name
+}
+
+// Equal returns true if both ServiceNames are equal.
+func (n ServiceName) Equal(o ServiceName) bool {
+	return n == o
+}
+
+// ParseServiceName parses the "[cluster/]namespace/name" representation of a
+// service name produced by ServiceName.String.
+func ParseServiceName(s string) ServiceName {
+	parts := strings.Split(s, "/")
+	switch len(parts) {
+	case 3:
+		return ServiceName{Cluster: parts[0], Namespace: parts[1], Name: parts[2]}
+	case 2:
+		return ServiceName{Namespace: parts[0], Name: parts[1]}
+	default:
+		return ServiceName{Name: s}
+	}
+}
+
+// MarshalYAML marshals the ServiceName to its string representation.
+func (n ServiceName) MarshalYAML() (any, error) {
+	return n.String(), nil
+}
+
+// UnmarshalYAML unmarshals the ServiceName from its string representation.
+func (n *ServiceName) UnmarshalYAML(unmarshal func(any) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	*n = ParseServiceName(s)
+	return nil
+}
+
+var _ yaml.Marshaler = ServiceName{}
+var _ yaml.Unmarshaler = &ServiceName{}
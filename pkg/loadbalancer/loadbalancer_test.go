@@ -5,6 +5,7 @@ package loadbalancer
 
 import (
 	"bytes"
+	"strconv"
 	"strings"
 	"testing"
 
@@ -143,6 +144,8 @@ func TestL3n4AddrYAML(t *testing.T) {
 		"1.1.1.1:65535/UDP",
 		"[2001::1]:80/TCP",
 		"[2001::1]:80/SCTP",
+		"1.1.1.1:22,3389/TCP",
+		"1.1.1.1:8000-8100/TCP",
 	}
 	for _, test := range tests {
 		var l L3n4Addr
@@ -158,6 +161,137 @@ func TestL3n4AddrYAML(t *testing.T) {
 	}
 }
 
+func TestL3n4AddrUnixSocket(t *testing.T) {
+	tests := []struct {
+		protocol L4Type
+		path     string
+		want     string
+	}{
+		{protocol: UNIX, path: "/var/run/foo.sock", want: "unix:///var/run/foo.sock"},
+		{protocol: UNIXGRAM, path: "@abstract-socket", want: "unix-abstract://@abstract-socket"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			a, err := NewUnixL3n4Addr(tt.protocol, tt.path, ScopeExternal)
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.True(t, a.IsUnixSocket())
+			assert.Equal(t, tt.path, a.Path)
+			assert.Equal(t, tt.want, a.String())
+
+			var parsed L3n4Addr
+			if assert.NoError(t, parsed.ParseFromString(tt.want)) {
+				assert.True(t, a.DeepEqual(&parsed))
+			}
+
+			out, err := yaml.Marshal(a)
+			if assert.NoError(t, err) {
+				assert.Equal(t, tt.want, strings.Trim(string(out), "\n'"))
+				var l2 L3n4Addr
+				assert.NoError(t, yaml.Unmarshal(out, &l2))
+				assert.True(t, a.DeepEqual(&l2))
+			}
+		})
+	}
+
+	_, err := NewUnixL3n4Addr(TCP, "/var/run/foo.sock", ScopeExternal)
+	assert.Error(t, err, "TCP is not a Unix Domain Socket protocol")
+
+	_, err = NewUnixL3n4Addr(UNIX, "", ScopeExternal)
+	assert.Error(t, err, "empty path")
+}
+
+func TestL4Ports(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		want    string
+		port    uint16
+		wantLen int
+	}{
+		{name: "single port", spec: "8080", want: "8080"},
+		{name: "range", spec: "8000-8100", want: "8000-8100", wantLen: 1},
+		{name: "list", spec: "22,3389", want: "22,3389", wantLen: 2},
+		{name: "mixed, unsorted, overlapping", spec: "3389,22,8000-8100,8050-8200", want: "22,3389,8000-8200", wantLen: 3},
+		{name: "duplicate at max port", spec: "65535,65535", want: "65535"},
+		{name: "adjacent at max port", spec: "65534-65535,65535", want: "65534-65535", wantLen: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			port, ports, err := ParsePorts(tt.spec)
+			if !assert.NoError(t, err) {
+				return
+			}
+			if tt.wantLen == 0 {
+				assert.Nil(t, ports)
+				assert.Equal(t, tt.want, strconv.FormatUint(uint64(port), 10))
+				return
+			}
+			assert.Len(t, ports, tt.wantLen)
+			assert.Equal(t, tt.want, ports.String())
+		})
+	}
+
+	_, list, err := ParsePorts("22,3389,8000-8100")
+	if assert.NoError(t, err) {
+		assert.True(t, list.Contains(22))
+		assert.True(t, list.Contains(8050))
+		assert.False(t, list.Contains(23))
+		assert.True(t, list.Overlaps(L4Ports{{From: 8090, To: 8200}}))
+		assert.False(t, list.Overlaps(L4Ports{{From: 9000, To: 9001}}))
+	}
+}
+
+func TestL3n4AddrURIForm(t *testing.T) {
+	tests := []string{
+		"tcp://1.1.1.1:80",
+		"tcp://1.1.1.1:80?scope=internal",
+		"udp://[2001::1]:53",
+	}
+	for _, test := range tests {
+		var a L3n4Addr
+		if assert.NoError(t, a.ParseFromString(test), "parse %q", test) {
+			assert.Equal(t, test, a.ToURI())
+		}
+	}
+
+	// A bare "ip:port" without a scheme defaults to protocol NONE, per the
+	// legacy parser's defaulted-protocol shorthand.
+	var a L3n4Addr
+	if assert.NoError(t, a.ParseFromString("1.1.1.1:80")) {
+		assert.Equal(t, NONE, a.Protocol)
+	}
+}
+
+func TestParseL3n4AddrList(t *testing.T) {
+	doc := []byte(`{
+		// host-1 is the staging frontend
+		"hosts": {"host-1": "100.100.100.100"},
+		"addrs": [
+			"host-1:80/TCP",
+			"1.2.3.4:443/TCP"
+		]
+	}`)
+
+	addrs, err := ParseL3n4AddrList(doc)
+	if assert.NoError(t, err) && assert.Len(t, addrs, 2) {
+		assert.Equal(t, "100.100.100.100:80/TCP", addrs[0].String())
+		assert.Equal(t, "1.2.3.4:443/TCP", addrs[1].String())
+	}
+
+	arr, err := ParseL3n4AddrList([]byte(`["1.1.1.1:80/TCP", "2.2.2.2:90/UDP"]`))
+	if assert.NoError(t, err) && assert.Len(t, arr, 2) {
+		assert.Equal(t, "1.1.1.1:80/TCP", arr[0].String())
+	}
+
+	_, err = ParseL3n4AddrList([]byte(`{"addrs": ["not-an-address"]}`))
+	var perr *ParseError
+	if assert.Error(t, err) && assert.ErrorAs(t, err, &perr) {
+		assert.Equal(t, 1, perr.Line)
+	}
+}
+
 func TestL3n4AddrID_Equals(t *testing.T) {
 	type args struct {
 		o *L3n4AddrID
@@ -618,6 +752,17 @@ func TestNewSvcFlag(t *testing.T) {
 			}
 		})
 	}
+
+	t.Run("UDS frontend forces non-routable", func(t *testing.T) {
+		p := &SvcFlagParam{
+			SvcType:    SVCTypeClusterIP,
+			IsRoutable: true,
+			Path:       "/var/run/foo.sock",
+		}
+		if got := NewSvcFlag(p); got&serviceFlagRoutable != 0 {
+			t.Errorf("NewSvcFlag() = %v, want serviceFlagRoutable unset despite IsRoutable=true", got)
+		}
+	})
 }
 
 func TestServiceFlags_String(t *testing.T) {
@@ -751,6 +896,16 @@ func BenchmarkL3n4Addr_Hash_IPv6_Max(b *testing.B) {
 	benchmarkHash(b, addr)
 }
 
+func BenchmarkL3n4Addr_Hash_PortRange(b *testing.B) {
+	addr := NewL3n4Addr(TCP, cmtypes.MustParseAddrCluster("1.2.3.4"), 0, ScopeInternal)
+	_, ports, err := ParsePorts("8000-8100")
+	if err != nil {
+		b.Fatal(err)
+	}
+	addr.Ports = ports
+	benchmarkHash(b, addr)
+}
+
 func benchmarkString(b *testing.B, addr *L3n4Addr) {
 	b.ReportAllocs()
 
@@ -787,3 +942,13 @@ func BenchmarkL3n4Addr_StringWithProtocol_IPv6_Max(b *testing.B) {
 	addr := NewL3n4Addr(TCP, cmtypes.MustParseAddrCluster("1020:3040:5060:7080:90a0:b0c0:d0e0:f000"), 30303, 100)
 	benchmarkStringWithProtocol(b, addr)
 }
+
+func BenchmarkL3n4Addr_String_PortRange(b *testing.B) {
+	addr := NewL3n4Addr(TCP, cmtypes.MustParseAddrCluster("192.168.123.210"), 0, ScopeExternal)
+	_, ports, err := ParsePorts("22,3389,8000-8100")
+	if err != nil {
+		b.Fatal(err)
+	}
+	addr.Ports = ports
+	benchmarkString(b, addr)
+}
@@ -0,0 +1,350 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package loadbalancer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	cmtypes "github.com/cilium/cilium/pkg/clustermesh/types"
+)
+
+// l3n4AddrParser attempts to parse s into *out. It returns matched == true
+// once it has recognized the form of s, at which point err (nil or not) is
+// final and no further parser in l3n4AddrParsers is consulted. Returning
+// matched == false lets ParseFromString fall through to the next parser in
+// the registry.
+type l3n4AddrParser func(s string, out *L3n4Addr) (matched bool, err error)
+
+// l3n4AddrParsers is the registry of forms accepted by
+// L3n4Addr.ParseFromString, tried in order.
+var l3n4AddrParsers = []l3n4AddrParser{
+	parseUnixL3n4Addr,
+	parseURIL3n4Addr,
+	parseLegacyL3n4Addr,
+}
+
+// parseUnixL3n4Addr recognizes the `unix://path` and `unix-abstract://path`
+// forms.
+func parseUnixL3n4Addr(s string, out *L3n4Addr) (bool, error) {
+	if rest, ok := strings.CutPrefix(s, "unix://"); ok {
+		*out = L3n4Addr{L4Addr: L4Addr{Protocol: UNIX}, Path: rest}
+		return true, nil
+	}
+	if rest, ok := strings.CutPrefix(s, "unix-abstract://"); ok {
+		*out = L3n4Addr{L4Addr: L4Addr{Protocol: UNIXGRAM}, Path: rest}
+		return true, nil
+	}
+	return false, nil
+}
+
+// parseURIL3n4Addr recognizes the `proto://host:port?scope=...&cluster=...`
+// URI form, e.g. `tcp://1.2.3.4:80?scope=internal&cluster=3`. The scheme
+// selects the protocol; omitting it (a bare `host:port`) is handled by
+// parseLegacyL3n4Addr instead.
+func parseURIL3n4Addr(s string, out *L3n4Addr) (bool, error) {
+	idx := strings.Index(s, "://")
+	if idx == -1 {
+		return false, nil
+	}
+	scheme := s[:idx]
+	if scheme == "unix" || scheme == "unix-abstract" {
+		// Handled by parseUnixL3n4Addr; don't also report a match failure.
+		return false, nil
+	}
+
+	protocol := L4Type(strings.ToUpper(scheme))
+	switch protocol {
+	case NONE, TCP, UDP, SCTP:
+	default:
+		return false, fmt.Errorf("unknown URI scheme %q", scheme)
+	}
+
+	// The authority is split by hand rather than with url.Parse, which
+	// rejects port lists and ranges such as `:22,3389`.
+	hostPort, rawQuery, _ := strings.Cut(s[idx+len("://"):], "?")
+	q, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return true, fmt.Errorf("invalid URI %q: %w", s, err)
+	}
+
+	host, portStr, err := net.SplitHostPort(hostPort)
+	if err != nil {
+		return true, fmt.Errorf("invalid URI host %q: %w", hostPort, err)
+	}
+	port, ports, err := ParsePorts(portStr)
+	if err != nil {
+		return true, err
+	}
+
+	var scope uint8
+	switch s := q.Get("scope"); s {
+	case "", "external":
+		scope = ScopeExternal
+	case "internal":
+		scope = ScopeInternal
+	default:
+		return true, fmt.Errorf("unknown scope %q", s)
+	}
+
+	if cluster := q.Get("cluster"); cluster != "" {
+		host = host + "@" + cluster
+	}
+
+	addrCluster, err := cmtypes.ParseAddrCluster(host)
+	if err != nil {
+		return true, fmt.Errorf("invalid URI address %q: %w", host, err)
+	}
+
+	*out = L3n4Addr{
+		L4Addr:      L4Addr{Protocol: protocol, Port: port, Ports: ports},
+		AddrCluster: addrCluster,
+		Scope:       scope,
+	}
+	return true, nil
+}
+
+// parseLegacyL3n4Addr recognizes the canonical `ip:port/PROTO[/scope]` form
+// as well as the defaulted-protocol `ip:port` shorthand (b), which is just
+// the same grammar without a trailing `/PROTO`.
+func parseLegacyL3n4Addr(s string, out *L3n4Addr) (bool, error) {
+	var scope uint8
+	if rest, ok := strings.CutSuffix(s, "/i"); ok {
+		scope = ScopeInternal
+		s = rest
+	}
+
+	protocol := NONE
+	if idx := strings.LastIndex(s, "/"); idx != -1 {
+		protocol = L4Type(s[idx+1:])
+		s = s[:idx]
+	}
+	switch protocol {
+	case NONE, TCP, UDP, SCTP:
+	default:
+		return true, fmt.Errorf("unknown protocol %q", protocol)
+	}
+
+	host, portStr, err := net.SplitHostPort(s)
+	if err != nil {
+		return true, fmt.Errorf("invalid address %q: %w", s, err)
+	}
+	port, ports, err := ParsePorts(portStr)
+	if err != nil {
+		return true, err
+	}
+
+	addrCluster, err := cmtypes.ParseAddrCluster(host)
+	if err != nil {
+		return true, fmt.Errorf("invalid address %q: %w", host, err)
+	}
+
+	*out = L3n4Addr{
+		L4Addr:      L4Addr{Protocol: protocol, Port: port, Ports: ports},
+		AddrCluster: addrCluster,
+		Scope:       scope,
+	}
+	return true, nil
+}
+
+// ParseError is returned by ParseL3n4AddrList when an entry in the document
+// fails to parse. Line and Column are 1-based and point at the offending
+// entry within the original (uncommented) document passed to
+// ParseL3n4AddrList.
+type ParseError struct {
+	Line   int
+	Column int
+	Err    error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// l3n4AddrListDocument is the shape accepted by ParseL3n4AddrList: either a
+// bare JSON array of address strings, or an object carrying named host
+// aliases alongside the address list, similar to how ACL policy files let
+// you write `host-1: 100.100.100.100` and reference `host-1` elsewhere.
+type l3n4AddrListDocument struct {
+	Hosts map[string]string `json:"hosts,omitempty"`
+	Addrs []string          `json:"addrs"`
+}
+
+// ParseL3n4AddrList parses a batch document of L3n4Addr, in JSON or
+// hujson-flavored JSON (i.e. JSON with `//` line comments stripped before
+// decoding). The document is either a bare array of address strings, e.g.
+//
+//	["1.1.1.1:80/TCP", "unix:///var/run/foo.sock"]
+//
+// or an object with a "hosts" map of named AddrCluster aliases that "addrs"
+// entries may use in place of the literal address, e.g.
+//
+//	{
+//	  "hosts": {"host-1": "100.100.100.100"},
+//	  "addrs": ["host-1:80/TCP"]
+//	}
+//
+// Entries that fail to parse are reported as a *ParseError pointing at the
+// line/column of the failing entry in doc.
+func ParseL3n4AddrList(doc []byte) ([]L3n4Addr, error) {
+	stripped := stripLineComments(doc)
+
+	var parsed l3n4AddrListDocument
+	if err := json.Unmarshal(stripped, &parsed); err != nil {
+		var addrs []string
+		if arrErr := json.Unmarshal(stripped, &addrs); arrErr != nil {
+			return nil, newSyntaxParseError(stripped, err)
+		}
+		parsed.Addrs = addrs
+	}
+
+	hosts := make(map[string]string, len(parsed.Hosts))
+	for name, addr := range parsed.Hosts {
+		if _, err := cmtypes.ParseAddrCluster(addr); err != nil {
+			return nil, fmt.Errorf("host alias %q: invalid address %q: %w", name, addr, err)
+		}
+		hosts[name] = addr
+	}
+
+	out := make([]L3n4Addr, 0, len(parsed.Addrs))
+	for _, entry := range parsed.Addrs {
+		resolved := entry
+		for alias, addr := range hosts {
+			if rest, ok := strings.CutPrefix(entry, alias+":"); ok {
+				resolved = addr + ":" + rest
+				break
+			}
+		}
+
+		var a L3n4Addr
+		if err := a.ParseFromString(resolved); err != nil {
+			return nil, entryParseError(stripped, entry, err)
+		}
+		out = append(out, a)
+	}
+
+	return out, nil
+}
+
+// stripLineComments turns `//`-prefixed (hujson-style) line comments into
+// blank lines, preserving line numbers for ParseError reporting. A `//`
+// inside a JSON string (e.g. `"unix:///var/run/foo.sock"`) is left alone.
+// It's a best-effort pass, not a full hujson implementation: block comments
+// and trailing commas are not handled.
+func stripLineComments(doc []byte) []byte {
+	lines := bytes.Split(doc, []byte("\n"))
+	for i, line := range lines {
+		inString, escaped := false, false
+	scan:
+		for j, c := range line {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = inString
+			case c == '"':
+				inString = !inString
+			case !inString && c == '/' && j+1 < len(line) && line[j+1] == '/':
+				lines[i] = line[:j]
+				break scan
+			}
+		}
+	}
+	return bytes.Join(lines, []byte("\n"))
+}
+
+// offsetToLineColumn converts a 0-based byte offset into doc to a 1-based
+// (line, column) pair.
+func offsetToLineColumn(doc []byte, offset int) (line, column int) {
+	if offset > len(doc) {
+		offset = len(doc)
+	}
+	line = 1 + bytes.Count(doc[:offset], []byte("\n"))
+	if idx := bytes.LastIndexByte(doc[:offset], '\n'); idx != -1 {
+		column = offset - idx
+	} else {
+		column = offset + 1
+	}
+	return line, column
+}
+
+func newSyntaxParseError(doc []byte, err error) error {
+	var offset int
+	if se, ok := err.(*json.SyntaxError); ok {
+		offset = int(se.Offset)
+	} else if te, ok := err.(*json.UnmarshalTypeError); ok {
+		offset = int(te.Offset)
+	}
+	line, col := offsetToLineColumn(doc, offset)
+	return &ParseError{Line: line, Column: col, Err: err}
+}
+
+func entryParseError(doc []byte, entry string, err error) error {
+	line, col := 1, 1
+	if idx := bytes.Index(doc, []byte(entry)); idx != -1 {
+		line, col = offsetToLineColumn(doc, idx)
+	}
+	return &ParseError{Line: line, Column: col, Err: fmt.Errorf("entry %q: %w", entry, err)}
+}
+
+// ToURI renders a in the `proto://host:port?scope=...&cluster=...` URI form
+// accepted by parseURIL3n4Addr. Unix Domain Socket addresses render the same
+// as String(), since they're already URI-shaped.
+func (a L3n4Addr) ToURI() string {
+	if a.IsUnixSocket() {
+		return a.String()
+	}
+
+	host := a.AddrCluster.Addr().String()
+	var portStr string
+	if len(a.Ports) > 0 {
+		portStr = a.Ports.String()
+	} else {
+		portStr = fmt.Sprintf("%d", a.Port)
+	}
+
+	q := url.Values{}
+	if a.Scope == ScopeInternal {
+		q.Set("scope", "internal")
+	}
+	if cluster := a.AddrCluster.ClusterID(); cluster != 0 {
+		q.Set("cluster", fmt.Sprintf("%d", cluster))
+	}
+
+	u := url.URL{
+		Scheme:   strings.ToLower(string(a.Protocol)),
+		Host:     net.JoinHostPort(host, portStr),
+		RawQuery: q.Encode(),
+	}
+	return u.String()
+}
+
+// L3n4AddrURI wraps a L3n4Addr so that it marshals to/from YAML using the
+// URI form (ToURI/parseURIL3n4Addr) instead of the default compact legacy
+// form produced by L3n4Addr's own MarshalYAML. Use this type for fields that
+// should be serialized as URIs, e.g. when round-tripping through tooling
+// that also accepts the URI form on the command line.
+type L3n4AddrURI struct {
+	L3n4Addr
+}
+
+func (a L3n4AddrURI) MarshalYAML() (any, error) {
+	return a.L3n4Addr.ToURI(), nil
+}
+
+func (a *L3n4AddrURI) UnmarshalYAML(unmarshal func(any) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	return a.L3n4Addr.ParseFromString(s)
+}